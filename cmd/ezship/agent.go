@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wendelmax/ezship/internal/wsl"
+)
+
+var (
+	agentOnce          bool
+	agentInterval      time.Duration
+	agentNonDisruptive bool
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Run the scheduled maintenance jobs from the config (prune, vacuum) when they are due",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := wsl.LoadConfig()
+		for _, j := range cfg.Maintenance {
+			if err := j.Validate(); err != nil {
+				fmt.Printf("Warning: %v (job ignored)\n", err)
+			}
+		}
+		if !agentOnce {
+			if len(cfg.Maintenance) == 0 {
+				fmt.Println("No maintenance jobs configured (see \"maintenance\" in the config); waiting for some.")
+			} else {
+				printSchedule(cfg)
+			}
+		}
+
+		for {
+			runs, err := wsl.RunDueMaintenance(agentNonDisruptive)
+			for _, r := range runs {
+				if r.Error != "" {
+					fmt.Printf("[%s] %s failed: %s\n", r.Time.Format("2006-01-02 15:04"), r.Job, r.Error)
+				} else {
+					fmt.Printf("[%s] %s: %s\n", r.Time.Format("2006-01-02 15:04"), r.Job, r.Result)
+				}
+			}
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				if agentOnce {
+					os.Exit(1)
+				}
+			}
+			if agentOnce {
+				return
+			}
+			time.Sleep(agentInterval)
+		}
+	},
+}
+
+// printSchedule lists the jobs with their last and next runs
+func printSchedule(cfg wsl.Config) {
+	s := wsl.NewScheduler(cfg)
+	state := wsl.LoadMaintenanceState()
+	fmt.Printf("%-24s %-17s %s\n", "JOB", "LAST RUN", "NEXT RUN")
+	for _, j := range cfg.Maintenance {
+		next, err := s.NextRun(j, state)
+		if err != nil {
+			continue
+		}
+		last := "never"
+		if run, ok := state.Jobs[j.ID()]; ok {
+			last = run.Time.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%-24s %-17s %s\n", j.ID(), last, next.Local().Format("2006-01-02 15:04"))
+	}
+}
+
+func init() {
+	agentCmd.Flags().BoolVar(&agentOnce, "once", false, "Run the overdue jobs and exit")
+	agentCmd.Flags().BoolVar(&agentNonDisruptive, "non-disruptive", false, "Skip jobs that stop the distro (vacuum)")
+	agentCmd.Flags().DurationVar(&agentInterval, "interval", time.Minute, "How often to check for due jobs")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wendelmax/ezship/internal/wsl"
+)
+
+var aliasForce bool
+
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Manage the global proxy aliases (docker.exe, kubectl.exe, ...)",
+}
+
+var aliasListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show each proxy alias and whether it is current, stale or shadowed",
+	Run: func(cmd *cobra.Command, args []string) {
+		aliases, err := wsl.ListAliases()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%-10s %-10s %-14s %s\n", "ALIAS", "STATE", "VERSION", "NOTES")
+		fmt.Println(strings.Repeat("-", 60))
+		for _, a := range aliases {
+			version := a.Version
+			if version == "" {
+				version = "-"
+			}
+			notes := ""
+			if a.ShadowedBy != "" {
+				notes = "shadowed by " + a.ShadowedBy
+			}
+			fmt.Printf("%-10s %-10s %-14s %s\n", a.Name, a.State, version, notes)
+		}
+	},
+}
+
+var aliasCreateCmd = &cobra.Command{
+	Use:   "create [alias...]",
+	Short: "Create proxy aliases (all known aliases if none given)",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			args = wsl.AllAliases(wsl.LoadConfig())
+		}
+		failed := false
+		for _, name := range args {
+			name = strings.ToLower(name)
+			if !wsl.IsProxyAlias(name) {
+				fmt.Printf("Error: %s is not a proxy alias (add it to extra_aliases in the config)\n", name)
+				failed = true
+				continue
+			}
+			if err := wsl.CreateAlias(name, aliasForce); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+var aliasRemoveCmd = &cobra.Command{
+	Use:   "remove [alias...]",
+	Short: "Remove proxy aliases",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		failed := false
+		for _, name := range args {
+			name = strings.ToLower(name)
+			if err := wsl.RemoveAlias(name, aliasForce); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				failed = true
+				continue
+			}
+			fmt.Printf("Removed global alias: %s\n", name)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+var aliasRepairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Refresh proxy aliases left over from an older ezship version",
+	Run: func(cmd *cobra.Command, args []string) {
+		repaired, err := wsl.RepairAliases()
+		for _, name := range repaired {
+			fmt.Printf("Refreshed global alias: %s\n", name)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(repaired) == 0 {
+			fmt.Println("All aliases are up to date.")
+		}
+	},
+}
+
+func init() {
+	aliasCreateCmd.Flags().BoolVar(&aliasForce, "force", false, "Overwrite binaries that are not ezship proxies")
+	aliasRemoveCmd.Flags().BoolVar(&aliasForce, "force", false, "Remove binaries that are not ezship proxies")
+
+	aliasCmd.AddCommand(aliasListCmd)
+	aliasCmd.AddCommand(aliasCreateCmd)
+	aliasCmd.AddCommand(aliasRemoveCmd)
+	aliasCmd.AddCommand(aliasRepairCmd)
+}
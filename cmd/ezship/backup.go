@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wendelmax/ezship/internal/wsl"
+)
+
+var (
+	backupName   string
+	restoreForce bool
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Stop the engines and export the distro to a compressed backup",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("Backing up %s (engines will be stopped)...\n", wsl.DistroName)
+		info, err := wsl.CreateBackup(backupName)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Backup %s created (%s) in %s\n", info.Name, wsl.FormatSize(info.Size), wsl.GetBackupDir(wsl.LoadConfig()))
+	},
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List backups",
+	Run: func(cmd *cobra.Command, args []string) {
+		backups, err := wsl.ListBackups(wsl.LoadConfig())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(backups) == 0 {
+			fmt.Println("No backups yet. Create one with 'ezship backup'.")
+			return
+		}
+		fmt.Printf("%-32s %-12s %-17s %-10s %s\n", "NAME", "PROFILE", "CREATED", "SIZE", "ENGINES")
+		fmt.Println(strings.Repeat("-", 90))
+		for _, b := range backups {
+			fmt.Printf("%-32s %-12s %-17s %-10s %s\n", b.Name, b.Profile, b.Created.Local().Format("2006-01-02 15:04"),
+				wsl.FormatSize(b.Size), strings.Join(b.Engines, ", "))
+		}
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <backup>",
+	Short: "Re-import a backup as the distro of the active profile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("Restoring %s into %s...\n", args[0], wsl.DistroName)
+		if err := wsl.RestoreBackup(args[0], restoreForce); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Backup restored.")
+	},
+}
+
+func init() {
+	backupCmd.Flags().StringVar(&backupName, "name", "", "Backup name (default <profile>-<timestamp>)")
+	restoreCmd.Flags().BoolVar(&restoreForce, "force", false, "Replace the existing distro")
+
+	backupCmd.AddCommand(backupListCmd)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wendelmax/ezship/internal/wsl"
+)
+
+var (
+	bundleOutput  string
+	bundleEngines []string
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Create and check offline artifact bundles for air-gapped setups",
+}
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Download the rootfs and engine artifacts into a bundle directory",
+	Long: `Download the rootfs of the configured base image and the artifacts of the selected engines
+(.deb or .apk packages, the k3s binary with its airgap images, the nerdctl tarball, ...) into a directory,
+together with a manifest of checksums. Copy the directory to an offline machine and run:
+
+  ezship setup --cache <dir> docker`,
+	Run: func(cmd *cobra.Command, args []string) {
+		for i, e := range bundleEngines {
+			bundleEngines[i] = strings.ToLower(strings.TrimSpace(e))
+		}
+		if err := wsl.CreateBundle(bundleOutput, bundleEngines); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var bundleVerifyCmd = &cobra.Command{
+	Use:   "verify <dir>",
+	Short: "Check the checksums of a bundle",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		m, err := wsl.LoadBundleManifest(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := m.Verify(args[0]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Bundle OK: %d files, engines: %s (created by ezship %s)\n", len(m.Files), strings.Join(m.Engines, ", "), m.Version)
+	},
+}
+
+func init() {
+	bundleCreateCmd.Flags().StringVarP(&bundleOutput, "output", "o", "ezship-bundle", "Directory to write the bundle to")
+	bundleCreateCmd.Flags().StringSliceVar(&bundleEngines, "engines", wsl.BundleEngines, "Engines to include")
+
+	bundleCmd.AddCommand(bundleCreateCmd)
+	bundleCmd.AddCommand(bundleVerifyCmd)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wendelmax/ezship/internal/wsl"
+)
+
+var dfJSON bool
+
+var dfCmd = &cobra.Command{
+	Use:   "df",
+	Short: "Show where disk space goes: engines, build cache, the distro filesystem and the vhdx",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if installed, _ := wsl.IsDistroInstalled(); !installed {
+			fmt.Printf("Error: distro %s is not installed\n", wsl.DistroName)
+			os.Exit(1)
+		}
+		r := wsl.GetDiskUsage()
+
+		if dfJSON {
+			out := struct {
+				wsl.DiskUsageReport
+				Advice []string `json:"advice,omitempty"`
+			}{r, r.Advice()}
+			data, err := json.MarshalIndent(out, "", "  ")
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(data))
+			return
+		}
+
+		fmt.Printf("%-10s %-18s %7s %7s %10s %12s\n", "SOURCE", "TYPE", "TOTAL", "ACTIVE", "SIZE", "RECLAIMABLE")
+		fmt.Println(strings.Repeat("-", 70))
+		for _, it := range r.Items {
+			fmt.Printf("%-10s %-18s %7s %7s %10s %12s\n", it.Source, it.Type, count(it.Count), count(it.Active),
+				wsl.FormatSize(it.Size), wsl.FormatSize(it.Reclaimable))
+		}
+		if len(r.Items) == 0 {
+			fmt.Println("(no running engine reported usage)")
+		}
+		fmt.Println()
+		fmt.Printf("Distro filesystem: %s used of %s\n", wsl.FormatSize(r.FilesystemUsed), wsl.FormatSize(r.FilesystemSize))
+		fmt.Printf("Disk file:         %s (%s)\n", r.VHDXPath, wsl.FormatSize(r.VHDXSize))
+		for _, e := range r.Errors {
+			fmt.Printf("Warning: %s\n", e)
+		}
+		if advice := r.Advice(); len(advice) > 0 {
+			fmt.Println()
+			for _, a := range advice {
+				fmt.Println(a)
+			}
+		}
+	},
+}
+
+// count renders a row count, with "-" where the source reports none
+func count(n *int) string {
+	if n == nil {
+		return "-"
+	}
+	return fmt.Sprint(*n)
+}
+
+func init() {
+	dfCmd.Flags().BoolVar(&dfJSON, "json", false, "Print the report as JSON")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wendelmax/ezship/internal/wsl"
+)
+
+var (
+	profileFlag string
+	envBase     string
+)
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Manage named ezship environments (profiles), each in its own WSL distro",
+}
+
+var envCreateCmd = &cobra.Command{
+	Use:   "create <name> [engine]",
+	Short: "Create a profile with its own distro, optionally installing an engine",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if err := wsl.CreateProfile(name, envBase); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := wsl.UseProfile(name, wsl.LoadConfig()); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Setting up distro %s for profile %s...\n", wsl.DistroName, name)
+		if err := wsl.SetupDistro(); err != nil {
+			fmt.Printf("Error setting up distro: %v\n", err)
+			os.Exit(1)
+		}
+		if len(args) == 2 {
+			fmt.Printf("Installing %s...\n", args[1])
+			if err := wsl.InstallEngine(args[1]); err != nil {
+				fmt.Printf("Error installing engine: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		fmt.Printf("Profile %s is ready. Use it with 'ezship --profile %s ...', %s=%s, or 'ezship env use %s'.\n",
+			name, name, wsl.ProfileEnvVar, name, name)
+	},
+}
+
+var envListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("  %-16s %-22s %-14s %s\n", "PROFILE", "DISTRO", "BASE", "INSTALL DIR")
+		for _, p := range wsl.ListProfiles(wsl.LoadConfig()) {
+			mark := " "
+			if p.Active {
+				mark = "*"
+			}
+			name := p.Name
+			if p.Default {
+				name += " (default)"
+			}
+			fmt.Printf("%s %-16s %-22s %-14s %s\n", mark, name, p.Distro, p.BaseImage, p.InstallDir)
+		}
+	},
+}
+
+var envUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Set the default profile used by ezship and the proxied commands",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := wsl.SetDefaultProfile(args[0]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Default profile is now %s.\n", args[0])
+	},
+}
+
+var envRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Unregister a profile's distro and delete its data",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := wsl.DeleteProfile(args[0]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Profile %s removed.\n", args[0])
+	},
+}
+
+func init() {
+	envCreateCmd.Flags().StringVar(&envBase, "base", "", "Base distribution for the profile's distro")
+
+	envCmd.AddCommand(envCreateCmd)
+	envCmd.AddCommand(envListCmd)
+	envCmd.AddCommand(envUseCmd)
+	envCmd.AddCommand(envRemoveCmd)
+}
+
+// selectProfile activates the profile from --profile, EZSHIP_PROFILE or the config
+func selectProfile(cmd *cobra.Command, args []string) {
+	if cmd == envCreateCmd {
+		return // the profile named in EZSHIP_PROFILE may be the one being created
+	}
+	cfg := wsl.LoadConfig()
+	if err := wsl.UseProfile(wsl.ResolveProfile(profileFlag, cfg), cfg); err != nil {
+		// stderr: this also runs before proxied commands, whose stdout may be redirected
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/wendelmax/ezship/internal/tui"
@@ -15,7 +18,8 @@ var rootCmd = &cobra.Command{
 	Use:     "ezship",
 	Version: wsl.Version,
 	Short:   "ezship is a lightweight multi-engine container manager for Windows via WSL2",
-	Long: `ezship simplifies container management on Windows by using WSL2 and Ubuntu.
+	Long: `ezship simplifies container management on Windows by using WSL2 and a minimal Linux distro
+(Ubuntu by default, or Debian / Alpine).
 It supports Docker, Podman, nerdctl, k3d, and Kubernetes (k3s) with a beautiful TUI dashboard.
 
 Author: Jackson Wendel Santos Sá <jacksonwendel@gmail.com>
@@ -38,6 +42,41 @@ func init() {
 	rootCmd.AddCommand(vacuumCmd)
 	rootCmd.AddCommand(resetCmd)
 	rootCmd.AddCommand(updateCmd)
+	rootCmd.AddCommand(aliasCmd)
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(shellCmd)
+	rootCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(bundleCmd)
+	rootCmd.AddCommand(envCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(moveCmd)
+	rootCmd.AddCommand(dfCmd)
+	rootCmd.AddCommand(agentCmd)
+	rootCmd.AddCommand(uninstallCmd)
+
+	vacuumCmd.Flags().BoolVar(&vacuumOpts.DryRun, "dry-run", false, "Only estimate how much space can be reclaimed")
+	vacuumCmd.Flags().StringVar(&vacuumOpts.Method, "method", "", "Compaction method: "+strings.Join(wsl.VacuumMethods, ", ")+" (default: optimize-vhd if available, else diskpart)")
+
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Profile (environment) to operate on (default from "+wsl.ProfileEnvVar+" or 'ezship env use')")
+	// Flags after the tool name belong to the tool; ezship's own (--profile, ...) come before it
+	runCmd.Flags().SetInterspersed(false)
+	rootCmd.PersistentFlags().BoolVar(&wsl.SkipSnapshots, "no-snapshot", false, "Skip the automatic snapshot before destructive operations")
+	rootCmd.PersistentPreRun = selectProfile
+
+	setupCmd.Flags().StringVar(&setupRootfs, "rootfs", "", "Import the distro from a local rootfs tarball or URL")
+	setupCmd.Flags().StringVar(&setupBase, "base", "", "Base distribution for a new distro: "+strings.Join(wsl.BaseImageNames(), ", ")+" (default "+wsl.DefaultBaseImage+")")
+	setupCmd.Flags().StringVar(&setupDir, "install-dir", "", "Directory for the distro's virtual disk, e.g. on another drive (default %APPDATA%\\ezship)")
+	setupCmd.Flags().StringVar(&setupCache, "cache", "", "Artifact cache (from 'ezship bundle create') for offline installs")
+
+	shellCmd.Flags().BoolVar(&shellRoot, "root", false, "Open the shell as root")
+	execCmd.Flags().BoolVar(&shellRoot, "root", false, "Run the command as root")
+
+	historyCmd.Flags().StringVar(&historyQuery.Engine, "engine", "", "Only show commands for this engine or tool")
+	historyCmd.Flags().BoolVar(&historyQuery.FailedOnly, "failed", false, "Only show commands that failed")
+	historyCmd.Flags().IntVarP(&historyQuery.Limit, "limit", "n", 20, "Maximum number of entries to show (0 for all)")
 }
 
 var statusCmd = &cobra.Command{
@@ -137,40 +176,70 @@ var updateCmd = &cobra.Command{
 	},
 }
 
-var pruneCmd = &cobra.Command{
-	Use:   "prune",
-	Short: "Remove unused containers and images from all engines",
-	Run: func(cmd *cobra.Command, args []string) {
-		wsl.PruneEngines()
-	},
-}
+var vacuumOpts wsl.VacuumOptions
 
 var vacuumCmd = &cobra.Command{
 	Use:   "vacuum",
-	Short: "Compact the WSL disk (vhdx) to recover space",
+	Short: "Trim and compact the WSL disk (vhdx) to recover space",
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := wsl.Vacuum(); err != nil {
+		res, err := wsl.Vacuum(vacuumOpts)
+		if err != nil {
 			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if vacuumOpts.DryRun {
+			fmt.Printf("Disk file:   %s (%s)\n", res.VHDXPath, wsl.FormatSize(res.Before))
+			fmt.Printf("Used inside: %s\n", wsl.FormatSize(res.Used))
+			fmt.Printf("Reclaimable: about %s with %s\n", wsl.FormatSize(res.Reclaimable), res.Method)
+			return
+		}
+		if res.Trimmed != "" {
+			fmt.Println(res.Trimmed)
+		}
+		if res.Method == wsl.VacuumSparse {
+			fmt.Println("Sparse mode enabled: the disk now shrinks by itself as space is freed.")
 		}
+		fmt.Printf("Before: %s\nAfter:  %s\nSaved:  %s\n", wsl.FormatSize(res.Before), wsl.FormatSize(res.After), wsl.FormatSize(res.Saved()))
 	},
 }
 
-var resetCmd = &cobra.Command{
-	Use:   "reset",
-	Short: "Unregister and delete the ezship WSL environment",
+var moveCmd = &cobra.Command{
+	Use:   "move <dir>",
+	Short: "Relocate the distro's virtual disk to another directory or drive",
+	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := wsl.ResetDistro(); err != nil {
+		if err := wsl.MoveDistro(args[0]); err != nil {
 			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
 		}
+		fmt.Printf("Distro %s now lives in %s\n", wsl.DistroName, wsl.GetInstallDir())
 	},
 }
 
+var (
+	setupRootfs string
+	setupCache  string
+	setupBase   string
+	setupDir    string
+)
+
 var setupCmd = &cobra.Command{
 	Use:   "setup [engine]",
 	Short: "Setup the ezship WSL distro and optionally install an engine (docker, podman, k3s, nerdctl, k3d)",
 	Run: func(cmd *cobra.Command, args []string) {
+		// 0. Remember an offline artifact cache for this and later installs
+		if setupCache != "" {
+			cfg := wsl.LoadConfig()
+			cfg.ArtifactCache = setupCache
+			if err := wsl.SaveConfig(cfg); err != nil {
+				fmt.Printf("Error saving config: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Using artifact cache %s\n", setupCache)
+		}
+
 		// 1. Setup Distro
-		if err := wsl.SetupDistro(); err != nil {
+		if err := wsl.SetupDistroWithOptions(wsl.SetupOptions{Rootfs: setupRootfs, Base: setupBase, InstallDir: setupDir}); err != nil {
 			fmt.Printf("Error setting up distro: %v\n", err)
 			os.Exit(1)
 		}
@@ -186,6 +255,86 @@ var setupCmd = &cobra.Command{
 	},
 }
 
+var runCmd = &cobra.Command{
+	Use:   "run <tool> [args...]",
+	Short: "Run any tool inside the ezship distro with Windows path translation",
+	Long: `Run any tool inside the ezship distro, e.g. 'ezship run helm list'.
+Arguments that look like Windows paths are translated, and the engine the tool
+depends on (see tool_engines in the config) is started first.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// Linux tool names are case-sensitive, so the name is passed through as typed
+		exitOnError(wsl.RunProxyCommand(args[0], args[1:]))
+	},
+}
+
+var historyQuery wsl.AuditQuery
+
+var historyCmd = &cobra.Command{
+	Use:   "history [search]",
+	Short: "Search the audit log of proxied commands",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := wsl.LoadConfig()
+		if !cfg.AuditLog {
+			fmt.Println("Audit log is disabled. Set \"audit_log\": true in the config to record proxied commands.")
+		}
+		if len(args) > 0 {
+			historyQuery.Contains = args[0]
+		}
+
+		entries, err := wsl.ReadAuditLog(cfg, historyQuery)
+		if err != nil {
+			fmt.Printf("Error reading audit log: %v\n", err)
+			os.Exit(1)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No matching commands.")
+			return
+		}
+
+		fmt.Printf("%-19s %-10s %-5s %-9s %s\n", "TIME", "ENGINE", "EXIT", "DURATION", "COMMAND")
+		fmt.Println(strings.Repeat("-", 70))
+		for _, e := range entries {
+			duration := time.Duration(e.DurationMs) * time.Millisecond
+			fmt.Printf("%-19s %-10s %-5d %-9s %s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.Engine, e.ExitCode, duration.Round(10*time.Millisecond), e.CommandLine())
+		}
+	},
+}
+
+var shellRoot bool
+
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Open an interactive shell in the ezship distro",
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnError(wsl.OpenShell(shellRoot))
+	},
+}
+
+var execCmd = &cobra.Command{
+	Use:   "exec -- <command> [args...]",
+	Short: "Run a one-off command in the ezship distro",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		exitOnError(wsl.ExecInDistro(args, shellRoot))
+	},
+}
+
+// exitOnError exits with the child's exit code (or 1) when a passthrough command fails.
+// Errors go to stderr, so they never end up in the command's piped output.
+func exitOnError(err error) {
+	if err == nil {
+		return
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
 var dashboardCmd = &cobra.Command{
 	Use:   "dashboard",
 	Short: "Open the ezship TUI dashboard",
@@ -194,17 +343,37 @@ var dashboardCmd = &cobra.Command{
 	},
 }
 
+// printDownloadProgress draws a progress bar for rootfs/artifact downloads on stderr
+func printDownloadProgress(name string, done, total int64) {
+	const width = 30
+	mb := float64(done) / (1 << 20)
+	if total <= 0 {
+		fmt.Fprintf(os.Stderr, "\rDownloading %s %.1f MB", name, mb)
+		return
+	}
+	filled := int(int64(width) * done / total)
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", width-filled)
+	fmt.Fprintf(os.Stderr, "\rDownloading %s [%s] %3d%% %.1f/%.1f MB", name, bar, done*100/total, mb, float64(total)/(1<<20))
+	if done >= total {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
 func main() {
+	wsl.DownloadProgress = printDownloadProgress
+
 	// Transparent Proxy Detection
-	// If the binary name is 'docker', 'podman', or 'nerdctl', proxy immediately
+	// If the binary name is one of the proxy aliases (docker, podman, ...), proxy immediately
 	exeName := strings.ToLower(filepath.Base(os.Args[0]))
 	exeName = strings.TrimSuffix(exeName, ".exe")
 
-	if exeName == "docker" || exeName == "podman" || exeName == "nerdctl" || exeName == "kubectl" || exeName == "k3d" {
-		if err := wsl.RunProxyCommand(exeName, os.Args[1:]); err != nil {
-			fmt.Println(err)
-			os.Exit(1)
-		}
+	if wsl.IsProxyAlias(exeName) {
+		selectProfile(nil, nil)
+		err := wsl.RunProxyCommand(exeName, os.Args[1:])
+		// Once the command is done; the background run leaves out vacuum, which would stop the
+		// distro under other commands and containers
+		wsl.TriggerOverdueMaintenance()
+		exitOnError(err)
 		return
 	}
 
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wendelmax/ezship/internal/wsl"
+)
+
+var pruneOpts wsl.PruneOptions
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune [engine...]",
+	Short: "Remove unused containers and images (volumes with --volumes) from the engines",
+	Long: `Remove stopped containers and unused images from the running engines, or only the ones named
+(` + strings.Join(wsl.PruneEngineNames, ", ") + `). Named volumes are kept unless --volumes is given.
+Use --dry-run to list what would be removed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		pruneOpts.Engines = args
+		results, err := wsl.Prune(pruneOpts)
+		for _, r := range results {
+			if !pruneOpts.DryRun || r.Skipped != "" || r.Err != nil {
+				fmt.Println(r.Summary())
+				continue
+			}
+			if len(r.Candidates) == 0 {
+				fmt.Printf("%s: nothing to remove\n", r.Engine)
+				continue
+			}
+			fmt.Printf("%s: would remove (about %s)\n", r.Engine, wsl.FormatSize(r.Reclaimed))
+			for _, c := range r.Candidates {
+				fmt.Printf("  %s\n", c)
+			}
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	pruneCmd.Flags().StringVar(&pruneOpts.Until, "until", "", "Only remove objects older than this duration, e.g. 72h (default prune_until in the config)")
+	pruneCmd.Flags().StringArrayVar(&pruneOpts.Labels, "label", nil, "Only remove objects with this label (key or key=value, repeatable)")
+	pruneCmd.Flags().BoolVar(&pruneOpts.Volumes, "volumes", false, "Also remove unused volumes")
+	pruneCmd.Flags().BoolVar(&pruneOpts.DryRun, "dry-run", false, "Only list what would be removed")
+}
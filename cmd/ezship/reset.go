@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wendelmax/ezship/internal/wsl"
+)
+
+var (
+	resetOpts wsl.ResetOptions
+	resetYes  bool
+)
+
+var resetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Delete the ezship WSL environment, optionally rebuilding it with the same engines",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if resetOpts.KeepVolumes {
+			resetOpts.Rebuild = true
+		}
+		fmt.Printf("This deletes distro %s with all its containers, images and volumes.\n", wsl.DistroName)
+		if resetOpts.Rebuild {
+			fmt.Println("It is then set up again with the engines installed now.")
+		}
+		if resetOpts.KeepVolumes {
+			fmt.Println("Named volumes are exported first and restored afterwards.")
+		}
+		if !resetYes && !confirm("Reset "+wsl.DistroName+"?") {
+			fmt.Println("Aborted.")
+			return
+		}
+
+		res, err := wsl.Reset(resetOpts)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !resetOpts.Rebuild {
+			fmt.Printf("Distro %s has been deleted. Run 'ezship setup' to create it again.\n", wsl.DistroName)
+			return
+		}
+		fmt.Printf("Distro %s has been rebuilt.\n", wsl.DistroName)
+		if len(res.Engines) > 0 {
+			fmt.Printf("Engines: %s\n", strings.Join(res.Engines, ", "))
+		}
+		if len(res.Volumes) > 0 {
+			fmt.Printf("Volumes restored: %s\n", strings.Join(res.Volumes, ", "))
+		}
+	},
+}
+
+func init() {
+	resetCmd.Flags().BoolVar(&resetOpts.Rebuild, "rebuild", false, "Set the distro up again and reinstall the engines that were installed")
+	resetCmd.Flags().BoolVar(&resetOpts.KeepVolumes, "keep-volumes", false, "Export named volumes first and restore them after the rebuild (implies --rebuild)")
+	resetCmd.Flags().BoolVarP(&resetYes, "yes", "y", false, "Do not ask for confirmation")
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wendelmax/ezship/internal/wsl"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Inspect and roll back the automatic snapshots taken before destructive operations",
+	Long: `With "auto_snapshot": true in the config, ezship exports the distro before reset,
+vacuum, prune and engine upgrades. Pass --no-snapshot to any command to skip it once.`,
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the snapshots of the active profile",
+	Run: func(cmd *cobra.Command, args []string) {
+		snapshots, err := wsl.ListSnapshots(wsl.LoadConfig())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(snapshots) == 0 {
+			fmt.Println("No snapshots. Enable them with \"auto_snapshot\": true in the config.")
+			return
+		}
+		fmt.Printf("%-36s %-20s %-17s %s\n", "NAME", "BEFORE", "CREATED", "SIZE")
+		fmt.Println(strings.Repeat("-", 85))
+		for _, s := range snapshots {
+			fmt.Printf("%-36s %-20s %-17s %s\n", s.Name, s.Reason, s.Created.Local().Format("2006-01-02 15:04"), wsl.FormatSize(s.Size))
+		}
+	},
+}
+
+var snapshotVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the snapshots of the active profile against their checksums",
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := wsl.LoadConfig()
+		snapshots, err := wsl.ListSnapshots(cfg)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		failed := false
+		for _, s := range snapshots {
+			if err := s.Verify(wsl.GetSnapshotDir(cfg)); err != nil {
+				fmt.Printf("FAIL %s: %v\n", s.Name, err)
+				failed = true
+				continue
+			}
+			fmt.Printf("OK   %s\n", s.Name)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+var snapshotRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Replace the distro with the most recent intact snapshot",
+	Run: func(cmd *cobra.Command, args []string) {
+		snap, err := wsl.RollbackSnapshot()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Rolled back to %s (taken before %s on %s).\n", snap.Name, snap.Reason, snap.Created.Local().Format("2006-01-02 15:04"))
+	},
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotVerifyCmd)
+	snapshotCmd.AddCommand(snapshotRollbackCmd)
+}
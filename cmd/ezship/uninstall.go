@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wendelmax/ezship/internal/wsl"
+)
+
+var (
+	uninstallOpts wsl.UninstallOptions
+	uninstallYes  bool
+)
+
+var uninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove everything ezship created: distros, aliases, PATH entry, data, kube contexts",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		items := wsl.PlanUninstall(uninstallOpts)
+		if len(items) == 0 {
+			fmt.Println("Nothing to remove: ezship left nothing on this machine.")
+			return
+		}
+		fmt.Println("The following will be removed:")
+		for _, item := range items {
+			fmt.Printf("  - %s\n", item.Description)
+		}
+		if !uninstallYes && !confirm("Remove all of the above?") {
+			fmt.Println("Aborted.")
+			return
+		}
+
+		errs := wsl.Uninstall(items)
+		for _, err := range errs {
+			fmt.Printf("Error: %v\n", err)
+		}
+		if len(errs) > 0 {
+			os.Exit(1)
+		}
+		fmt.Println("ezship has been uninstalled.")
+	},
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func init() {
+	uninstallCmd.Flags().BoolVar(&uninstallOpts.KeepConfig, "keep-config", false, "Keep config.json")
+	uninstallCmd.Flags().BoolVar(&uninstallOpts.KeepBackups, "keep-backups", false, "Keep backups and snapshots")
+	uninstallCmd.Flags().BoolVarP(&uninstallYes, "yes", "y", false, "Do not ask for confirmation")
+}
@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -12,9 +13,10 @@ import (
 )
 
 const (
-	maxLogs   = 50
-	logPanelH = 6
-	mainWidth = 73 // sidebar(20) + content(50) + gap(3)
+	historyRows = 12
+	maxLogs     = 50
+	logPanelH   = 6
+	mainWidth   = 73 // sidebar(20) + content(50) + gap(3)
 )
 
 type model struct {
@@ -27,6 +29,13 @@ type model struct {
 	eCursor   int
 	cCursor   int
 	sCursor   int
+	hCursor   int
+	bCursor   int
+	history   []wsl.AuditEntry
+	backups   []wsl.BackupInfo
+	usage     *wsl.DiskUsageReport // Cleanup view report, nil until loaded
+	jobsSeen  time.Time            // scheduled maintenance runs up to here are already logged
+	download  downloadProgressMsg  // active download, if any
 	config    wsl.Config
 	logs      []string // rolling log lines
 	logScroll int      // scroll offset (from bottom)
@@ -41,13 +50,23 @@ type engineActionMsg struct {
 }
 
 type maintenanceMsg struct {
-	task string
-	err  error
+	task   string
+	err    error
+	detail string // optional result shown instead of "completed"
 }
 
 type refreshMsg struct{}
 type enginesLoadedMsg []wsl.EngineInfo
 type distrosLoadedMsg []wsl.DistroInfo
+type historyLoadedMsg []wsl.AuditEntry
+type backupsLoadedMsg []wsl.BackupInfo
+type diskUsageMsg wsl.DiskUsageReport
+type jobRunsMsg []wsl.JobRun
+
+type downloadProgressMsg struct {
+	name        string
+	done, total int64
+}
 
 // --- Log helpers ---
 
@@ -64,13 +83,14 @@ func (m *model) addLog(line string) {
 
 func initialModel() model {
 	return model{
-		choices:  []string{"Dashboard", "Engines", "WSL Distros", "Cleanup", "Settings", "About", "Update", "Exit"},
+		choices:  []string{"Dashboard", "Engines", "WSL Distros", "Cleanup", "History", "Backups", "Settings", "About", "Update", "Exit"},
 		cursor:   0,
 		engines:  nil,
 		distros:  nil,
 		config:   wsl.LoadConfig(),
 		selected: "Dashboard",
 		logs:     []string{},
+		jobsSeen: time.Now(),
 	}
 }
 
@@ -115,14 +135,33 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, m.cmdStart()
 		case "t": // Stop
 			return m, m.cmdStop()
+		case "o": // Open a shell in the selected distro
+			if m.selected == "WSL Distros" && len(m.distros) > 0 {
+				name := m.distros[m.dCursor].Name
+				m.addLog("Opening shell in " + name + "...")
+				return m, m.cmdShell(name)
+			}
 		case "i": // Install engine
 			if m.selected == "Engines" && len(m.engines) > 0 {
 				engine := m.engines[m.eCursor].Name
 				m.addLog("Queued install for " + engine + "...")
 				return m, m.cmdInstall(engine)
 			}
+		case "b": // Backup the distro
+			if m.selected == "Backups" {
+				m.addLog("Backing up " + wsl.DistroName + " (engines will be stopped)...")
+				return m, m.cmdBackup()
+			}
 		case "r": // Manual refresh
 			m.addLog("Manual refresh triggered")
+			switch m.selected {
+			case "History":
+				return m, tea.Batch(func() tea.Msg { return refreshMsg{} }, m.cmdLoadHistory())
+			case "Backups":
+				return m, tea.Batch(func() tea.Msg { return refreshMsg{} }, m.cmdLoadBackups())
+			case "Cleanup":
+				return m, tea.Batch(func() tea.Msg { return refreshMsg{} }, m.cmdLoadDiskUsage())
+			}
 			return m, func() tea.Msg { return refreshMsg{} }
 		case "esc", "backspace", "left", "h":
 			m.selected = "Dashboard"
@@ -140,9 +179,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case maintenanceMsg:
 		if msg.err != nil {
 			m.addLog(fmt.Sprintf("ERROR [%s]: %s", msg.task, msg.err.Error()))
+		} else if msg.detail != "" {
+			m.addLog(fmt.Sprintf("OK [%s]: %s", msg.task, msg.detail))
 		} else {
 			m.addLog(fmt.Sprintf("OK [%s]: completed", msg.task))
 		}
+		switch msg.task {
+		case "Backup":
+			return m, m.cmdLoadBackups()
+		case "Prune", "Vacuum":
+			return m, m.cmdLoadDiskUsage()
+		}
 		return m, nil
 
 	case refreshMsg:
@@ -153,6 +200,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				distros, _ := wsl.ListDistros()
 				return distrosLoadedMsg(distros)
 			},
+			m.cmdLoadJobRuns(),
 			m.tickCmd(),
 		)
 
@@ -164,6 +212,47 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case distrosLoadedMsg:
 		m.distros = msg
 		return m, nil
+
+	case historyLoadedMsg:
+		m.history = msg
+		return m, nil
+
+	case backupsLoadedMsg:
+		m.backups = msg
+		if m.bCursor >= len(m.backups) {
+			m.bCursor = 0
+		}
+		return m, nil
+
+	case jobRunsMsg:
+		// Report runs of the background agent that finished since the last check
+		sort.Slice(msg, func(i, j int) bool { return msg[i].Time.Before(msg[j].Time) })
+		for _, r := range msg {
+			if !r.Time.After(m.jobsSeen) {
+				continue
+			}
+			if r.Error != "" {
+				m.addLog(fmt.Sprintf("ERROR [Scheduled %s]: %s", r.Job, r.Error))
+			} else {
+				m.addLog(fmt.Sprintf("OK [Scheduled %s]: %s", r.Job, r.Result))
+			}
+			m.jobsSeen = r.Time
+		}
+		return m, nil
+
+	case diskUsageMsg:
+		r := wsl.DiskUsageReport(msg)
+		m.usage = &r
+		return m, nil
+
+	case downloadProgressMsg:
+		if msg.total > 0 && msg.done >= msg.total {
+			m.download = downloadProgressMsg{}
+			m.addLog(fmt.Sprintf("OK [Download]: %s (%.1f MB)", msg.name, float64(msg.done)/(1<<20)))
+		} else {
+			m.download = msg
+		}
+		return m, nil
 	}
 
 	return m, nil
@@ -183,6 +272,10 @@ func (m *model) moveCursor(up bool) {
 		m.applyMovement(&m.eCursor, delta, len(m.engines)-1)
 	case "Cleanup":
 		m.applyMovement(&m.cCursor, delta, 1)
+	case "History":
+		m.applyMovement(&m.hCursor, delta, len(m.history)-1)
+	case "Backups":
+		m.applyMovement(&m.bCursor, delta, len(m.backups)-1)
 	case "Settings":
 		m.applyMovement(&m.sCursor, delta, 2)
 	default:
@@ -278,6 +371,13 @@ func (m *model) enterView() tea.Cmd {
 		}
 	case "Cleanup":
 		m.cCursor = 0
+		return m.cmdLoadDiskUsage()
+	case "History":
+		m.hCursor = 0
+		return m.cmdLoadHistory()
+	case "Backups":
+		m.bCursor = 0
+		return m.cmdLoadBackups()
 	case "Settings":
 		m.sCursor = 0
 		m.config = wsl.LoadConfig()
@@ -315,6 +415,13 @@ func (m *model) cmdStop() tea.Cmd {
 	}
 }
 
+// cmdShell suspends the TUI, runs a shell in the distro and resumes when it exits
+func (m *model) cmdShell(distro string) tea.Cmd {
+	return tea.ExecProcess(wsl.ShellCommand(distro, false), func(err error) tea.Msg {
+		return maintenanceMsg{task: "Shell " + distro, err: err}
+	})
+}
+
 func (m *model) cmdInstall(engine string) tea.Cmd {
 	return func() tea.Msg {
 		err := wsl.InstallEngine(engine)
@@ -324,15 +431,59 @@ func (m *model) cmdInstall(engine string) tea.Cmd {
 
 func (m *model) cmdPrune() tea.Cmd {
 	return func() tea.Msg {
-		err := wsl.PruneEngines()
-		return maintenanceMsg{task: "Prune", err: err}
+		results, err := wsl.Prune(wsl.PruneOptions{})
+		var summary []string
+		for _, r := range results {
+			summary = append(summary, r.Summary())
+		}
+		return maintenanceMsg{task: "Prune", err: err, detail: strings.Join(summary, ", ")}
 	}
 }
 
 func (m *model) cmdVacuum() tea.Cmd {
 	return func() tea.Msg {
-		err := wsl.Vacuum()
-		return maintenanceMsg{task: "Vacuum", err: err}
+		res, err := wsl.Vacuum(wsl.VacuumOptions{})
+		if err != nil {
+			return maintenanceMsg{task: "Vacuum", err: err}
+		}
+		return maintenanceMsg{task: "Vacuum", detail: res.Summary()}
+	}
+}
+
+func (m *model) cmdLoadHistory() tea.Cmd {
+	return func() tea.Msg {
+		entries, _ := wsl.ReadAuditLog(wsl.LoadConfig(), wsl.AuditQuery{Limit: historyRows})
+		return historyLoadedMsg(entries)
+	}
+}
+
+func (m *model) cmdLoadBackups() tea.Cmd {
+	return func() tea.Msg {
+		backups, _ := wsl.ListBackups(wsl.LoadConfig())
+		return backupsLoadedMsg(backups)
+	}
+}
+
+func (m *model) cmdBackup() tea.Cmd {
+	return func() tea.Msg {
+		_, err := wsl.CreateBackup("")
+		return maintenanceMsg{task: "Backup", err: err}
+	}
+}
+
+func (m *model) cmdLoadDiskUsage() tea.Cmd {
+	return func() tea.Msg {
+		return diskUsageMsg(wsl.GetDiskUsage())
+	}
+}
+
+func (m *model) cmdLoadJobRuns() tea.Cmd {
+	return func() tea.Msg {
+		var runs []wsl.JobRun
+		for _, r := range wsl.LoadMaintenanceState().Jobs {
+			runs = append(runs, r)
+		}
+		return jobRunsMsg(runs)
 	}
 }
 
@@ -345,6 +496,24 @@ func (m *model) cmdUpdate() tea.Cmd {
 
 // --- View ---
 
+// renderDiskUsage summarises the disk usage report for the Cleanup view
+func (m model) renderDiskUsage() string {
+	if m.usage == nil {
+		return "  Disk usage: loading...\n"
+	}
+	var b strings.Builder
+	r := m.usage
+	b.WriteString("  Disk Usage (CLI: ezship df)\n")
+	for _, it := range r.Items {
+		b.WriteString(fmt.Sprintf("    %-8s %-16s %9s (%s reclaimable)\n", it.Source, it.Type, wsl.FormatSize(it.Size), wsl.FormatSize(it.Reclaimable)))
+	}
+	b.WriteString(fmt.Sprintf("    Filesystem: %s used | Disk file: %s\n", wsl.FormatSize(r.FilesystemUsed), wsl.FormatSize(r.VHDXSize)))
+	for _, a := range r.Advice() {
+		b.WriteString("  " + lipgloss.NewStyle().Foreground(SecondaryColor).Render(a) + "\n")
+	}
+	return b.String()
+}
+
 func (m model) View() string {
 	var b strings.Builder
 
@@ -377,9 +546,9 @@ func (m model) View() string {
 
 	case "Cleanup":
 		content.WriteString(TitleStyle.Render("System Cleanup") + "\n\n")
-		content.WriteString("  Controls: [Enter] Run Selected Task\n\n")
+		content.WriteString("  Controls: [Enter] Run Selected Task | [r] Refresh\n\n")
 		tasks := []struct{ name, desc string }{
-			{"Prune Engines", "Remove unused containers/images"},
+			{"Prune Engines", "Remove unused containers/images (volumes are kept)"},
 			{"Vacuum Disk", "Compact WSL disk (vhdx)"},
 		}
 		for i, t := range tasks {
@@ -391,6 +560,53 @@ func (m model) View() string {
 			content.WriteString(fmt.Sprintf("%s%s\n", prefix, taskStyle.Render(t.name)))
 			content.WriteString(fmt.Sprintf("    %s\n\n", t.desc))
 		}
+		content.WriteString(m.renderDiskUsage())
+
+	case "History":
+		content.WriteString(TitleStyle.Render("Command History") + "\n\n")
+		content.WriteString("  Controls: [r] Refresh | CLI: ezship history\n\n")
+		if !m.config.AuditLog {
+			content.WriteString("  Audit log is disabled (audit_log in config)\n\n")
+		}
+		if len(m.history) == 0 {
+			content.WriteString("  No commands recorded yet.\n")
+		}
+		for i, e := range m.history {
+			prefix := "  "
+			if i == m.hCursor {
+				prefix = "> "
+			}
+			exitColor := SuccessColor
+			if e.ExitCode != 0 {
+				exitColor = ErrorColor
+			}
+			exit := lipgloss.NewStyle().Foreground(exitColor).Render(fmt.Sprintf("%3d", e.ExitCode))
+			line := e.CommandLine()
+			if len(line) > 32 {
+				line = line[:31] + "…"
+			}
+			content.WriteString(fmt.Sprintf("%s%s %s %s\n", prefix, e.Time.Local().Format("01-02 15:04"), exit, line))
+		}
+
+	case "Backups":
+		content.WriteString(TitleStyle.Render("Backups") + "\n\n")
+		content.WriteString("  Controls: [b] Backup Now | [r] Refresh\n")
+		content.WriteString("  Restore:  ezship restore <name>\n\n")
+		if len(m.backups) == 0 {
+			content.WriteString("  No backups yet.\n")
+		}
+		for i, bk := range m.backups {
+			prefix := "  "
+			if i == m.bCursor {
+				prefix = "> "
+			}
+			name := bk.Name
+			if len(name) > 24 {
+				name = name[:23] + "…"
+			}
+			size := lipgloss.NewStyle().Foreground(SecondaryColor).Render(fmt.Sprintf("%9s", wsl.FormatSize(bk.Size)))
+			content.WriteString(fmt.Sprintf("%s%-24s %s %s\n", prefix, name, size, bk.Created.Local().Format("01-02 15:04")))
+		}
 
 	case "Settings":
 		content.WriteString(TitleStyle.Render("Settings") + "\n\n")
@@ -414,7 +630,7 @@ func (m model) View() string {
 			}
 			content.WriteString(fmt.Sprintf("%s%-15s [%s]\n", prefix, s.name, lipgloss.NewStyle().Foreground(valStyle).Render(s.value)))
 		}
-		content.WriteString("\n  " + NormalStyle.Render(fmt.Sprintf("Distro: %s | v%s", wsl.DistroName, wsl.Version)))
+		content.WriteString("\n  " + NormalStyle.Render(fmt.Sprintf("Profile: %s | Distro: %s | v%s", wsl.ActiveProfile(), wsl.DistroName, wsl.Version)))
 
 	case "Engines":
 		content.WriteString(TitleStyle.Render("Engine Management") + "\n\n")
@@ -443,7 +659,7 @@ func (m model) View() string {
 
 	case "WSL Distros":
 		content.WriteString(TitleStyle.Render("WSL Distributions") + "\n\n")
-		content.WriteString("  Controls: [s] Start | [t] Stop | [r] Refresh\n\n")
+		content.WriteString("  Controls: [s] Start | [t] Stop | [o] Shell | [r] Refresh\n\n")
 		if len(m.distros) == 0 {
 			content.WriteString("  Loading...\n")
 		}
@@ -499,6 +715,9 @@ func (m model) View() string {
 	var logBuf strings.Builder
 	logBuf.WriteString(lipgloss.NewStyle().Foreground(SecondaryColor).Bold(true).Render("  Logs") +
 		lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("  [PgUp/PgDn scroll]") + "\n")
+	if m.download.name != "" {
+		logBuf.WriteString("  " + m.downloadBar() + "\n")
+	}
 	for _, line := range logLines {
 		logBuf.WriteString("  " + line + "\n")
 	}
@@ -512,6 +731,22 @@ func (m model) View() string {
 	return b.String()
 }
 
+// downloadBar renders the progress of the active download
+func (m model) downloadBar() string {
+	d := m.download
+	if len(d.name) > 20 {
+		d.name = d.name[:19] + "…"
+	}
+	if d.total <= 0 {
+		return fmt.Sprintf("Downloading %s: %.1f MB", d.name, float64(d.done)/(1<<20))
+	}
+	const width = 24
+	filled := int(int64(width) * d.done / d.total)
+	bar := lipgloss.NewStyle().Foreground(SuccessColor).Render(strings.Repeat("█", filled)) +
+		lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(strings.Repeat("░", width-filled))
+	return fmt.Sprintf("Downloading %s %s %3d%%", d.name, bar, d.done*100/d.total)
+}
+
 // visibleLogs returns the last N log lines respecting logScroll offset
 func (m model) visibleLogs() []string {
 	total := len(m.logs)
@@ -546,6 +781,9 @@ func (m model) visibleLogs() []string {
 
 func Start() {
 	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
+	wsl.DownloadProgress = func(name string, done, total int64) {
+		p.Send(downloadProgressMsg{name: name, done: done, total: total})
+	}
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Alas, there's been an error: %v", err)
 		os.Exit(1)
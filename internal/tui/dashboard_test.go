@@ -2,6 +2,7 @@ package tui
 
 import (
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/wendelmax/ezship/internal/wsl"
@@ -136,3 +137,97 @@ func TestRefreshMessages(t *testing.T) {
 		t.Error("Expected distros to be loaded")
 	}
 }
+
+func TestHistoryView(t *testing.T) {
+	m := initialModel()
+	m.selected = "History"
+
+	entries := []wsl.AuditEntry{{Engine: "docker", Args: []string{"ps"}}, {Engine: "docker", Args: []string{"images"}, ExitCode: 1}}
+	newM, _ := m.Update(historyLoadedMsg(entries))
+	m = newM.(model)
+	if len(m.history) != 2 {
+		t.Fatal("Expected history to be loaded")
+	}
+
+	newM, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	m = newM.(model)
+	if m.hCursor != 1 {
+		t.Errorf("Expected history cursor to move to 1, got %d", m.hCursor)
+	}
+	if !contains(m.View(), "docker images") {
+		t.Error("Expected history view to list recorded commands")
+	}
+}
+
+func TestDistroShellKey(t *testing.T) {
+	m := initialModel()
+	m.selected = "WSL Distros"
+	m.distros = []wsl.DistroInfo{{Name: "ezship", State: "Running"}}
+
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	if cmd == nil {
+		t.Error("Expected a command to open a shell in the selected distro")
+	}
+}
+
+func TestBackupsView(t *testing.T) {
+	m := initialModel()
+	m.selected = "Backups"
+
+	backups := []wsl.BackupInfo{{Name: "default-20260101-120000", Size: 3 << 30}, {Name: "before-upgrade", Size: 1 << 20}}
+	newM, _ := m.Update(backupsLoadedMsg(backups))
+	m = newM.(model)
+	if len(m.backups) != 2 {
+		t.Fatal("Expected backups to be loaded")
+	}
+	if !contains(m.View(), "before-upgrade") || !contains(m.View(), "3.0 GB") {
+		t.Error("Expected backups view to list names and sizes")
+	}
+
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	if cmd == nil {
+		t.Error("Expected [b] to start a backup")
+	}
+}
+
+func TestCleanupDiskUsage(t *testing.T) {
+	m := initialModel()
+	m.selected = "Cleanup"
+	if !contains(m.View(), "loading") {
+		t.Error("Expected a placeholder before the disk usage report loads")
+	}
+
+	report := wsl.DiskUsageReport{
+		Items:          []wsl.DiskUsageItem{{Source: "docker", Type: "Images", Size: 3 << 30, Reclaimable: 2 << 30}},
+		FilesystemUsed: 4 << 30,
+		VHDXSize:       10 << 30,
+	}
+	newM, _ := m.Update(diskUsageMsg(report))
+	m = newM.(model)
+	view := m.View()
+	if !contains(view, "Images") || !contains(view, "10.0 GB") {
+		t.Error("Expected the Cleanup view to show engine usage and the disk file size")
+	}
+	if !contains(view, "ezship prune") || !contains(view, "ezship vacuum") {
+		t.Error("Expected prune and vacuum advice")
+	}
+}
+
+func TestScheduledJobLogs(t *testing.T) {
+	m := initialModel()
+	runs := []wsl.JobRun{
+		{Job: "prune-monday", Time: m.jobsSeen.Add(-time.Hour), Result: "old run"},
+		{Job: "vacuum-daily", Time: m.jobsSeen.Add(time.Minute), Error: "diskpart failed"},
+	}
+	newM, _ := m.Update(jobRunsMsg(runs))
+	m = newM.(model)
+	if len(m.logs) != 1 || !contains(m.logs[0], "vacuum-daily") || !contains(m.logs[0], "ERROR") {
+		t.Fatalf("Expected only the new run to be logged, got %v", m.logs)
+	}
+
+	// Seen runs are not logged twice
+	newM, _ = m.Update(jobRunsMsg(runs))
+	if len(newM.(model).logs) != 1 {
+		t.Error("Expected the run to be logged once")
+	}
+}
@@ -0,0 +1,272 @@
+package wsl
+
+import (
+	"crypto/sha256"
+	"debug/buildinfo"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const modulePath = "github.com/wendelmax/ezship"
+
+// ProxyAliases are the built-in names under which the ezship binary acts as a transparent proxy
+var ProxyAliases = []string{"docker", "podman", "nerdctl", "kubectl", "k3d"}
+
+// AllAliases returns the built-in aliases followed by the extra ones from the config
+func AllAliases(cfg Config) []string {
+	aliases := append([]string{}, ProxyAliases...)
+	seen := make(map[string]bool)
+	for _, a := range aliases {
+		seen[a] = true
+	}
+	for _, a := range cfg.ExtraAliases {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == "" || a == "ezship" || seen[a] {
+			continue
+		}
+		seen[a] = true
+		aliases = append(aliases, a)
+	}
+	return aliases
+}
+
+// Alias states reported by InspectAlias
+const (
+	AliasCurrent = "current" // our proxy, identical to the running binary
+	AliasStale   = "stale"   // our proxy, but from another ezship build
+	AliasForeign = "foreign" // some other program (e.g. the real Docker CLI)
+	AliasMissing = "missing"
+)
+
+type AliasInfo struct {
+	Name       string
+	Path       string
+	State      string
+	Version    string
+	ShadowedBy string // another executable found earlier on PATH
+}
+
+var ldflagsVersion = regexp.MustCompile(`wsl\.Version=([^'"\s]+)`)
+
+// IsProxyAlias reports whether name is one of the proxyable tool names (built-in or configured)
+func IsProxyAlias(name string) bool {
+	for _, a := range AllAliases(LoadConfig()) {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// binaryVersion returns the ezship version embedded in a binary, or "" if it is not ezship
+func binaryVersion(path string) (string, bool) {
+	bi, err := buildinfo.ReadFile(path)
+	if err != nil || bi.Main.Path != modulePath {
+		return "", false
+	}
+	for _, s := range bi.Settings {
+		if s.Key == "-ldflags" {
+			if m := ldflagsVersion.FindStringSubmatch(s.Value); m != nil {
+				return m[1], true
+			}
+		}
+	}
+	return "unknown", true
+}
+
+// aliasDir returns the directory holding the proxy copies (next to the running binary)
+func aliasDir() (string, string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get executable path: %w", err)
+	}
+	return filepath.Dir(exePath), exePath, nil
+}
+
+// inspectAlias checks the proxy binary for name in dir against the binary at selfPath
+func inspectAlias(name, dir, selfPath string) AliasInfo {
+	info := AliasInfo{Name: name, Path: filepath.Join(dir, name+".exe"), State: AliasMissing}
+
+	if _, err := os.Stat(info.Path); err == nil {
+		version, ours := binaryVersion(info.Path)
+		info.Version = version
+		switch {
+		case !ours:
+			info.State = AliasForeign
+		default:
+			info.State = AliasStale
+			aliasHash, err1 := fileHash(info.Path)
+			selfHash, err2 := fileHash(selfPath)
+			if err1 == nil && err2 == nil && aliasHash == selfHash {
+				info.State = AliasCurrent
+			}
+		}
+	}
+
+	if found, err := exec.LookPath(name); err == nil {
+		if abs, err := filepath.Abs(found); err == nil && !strings.EqualFold(abs, info.Path) {
+			info.ShadowedBy = abs
+		}
+	}
+	return info
+}
+
+// InspectAlias reports the state of a single proxy alias
+func InspectAlias(name string) (AliasInfo, error) {
+	dir, self, err := aliasDir()
+	if err != nil {
+		return AliasInfo{}, err
+	}
+	return inspectAlias(name, dir, self), nil
+}
+
+// ListAliases reports the state of every known proxy alias
+func ListAliases() ([]AliasInfo, error) {
+	dir, self, err := aliasDir()
+	if err != nil {
+		return nil, err
+	}
+	var infos []AliasInfo
+	for _, name := range AllAliases(LoadConfig()) {
+		infos = append(infos, inspectAlias(name, dir, self))
+	}
+	return infos, nil
+}
+
+// writeProxyBinary copies the running executable to path, replacing any existing file.
+// A running .exe cannot be overwritten on Windows, but it can be renamed out of the way.
+func writeProxyBinary(selfPath, path string) error {
+	source, err := os.Open(selfPath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	tmpPath := path + ".new"
+	destination, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(destination, source); err != nil {
+		destination.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	destination.Close()
+
+	if _, err := os.Stat(path); err == nil {
+		oldPath := path + ".old"
+		os.Remove(oldPath)
+		if err := os.Rename(path, oldPath); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+		defer os.Remove(oldPath)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// CreateAlias writes the proxy binary for name. Current copies are kept, stale ones refreshed,
+// and a foreign binary is only replaced when force is set.
+func CreateAlias(name string, force bool) error {
+	dir, self, err := aliasDir()
+	if err != nil {
+		return err
+	}
+
+	info := inspectAlias(name, dir, self)
+	switch info.State {
+	case AliasCurrent:
+		return nil
+	case AliasForeign:
+		if !force {
+			fmt.Printf("Warning: %s is not an ezship proxy, leaving it untouched\n", info.Path)
+			return nil
+		}
+	}
+
+	if err := writeProxyBinary(self, info.Path); err != nil {
+		return fmt.Errorf("failed to create proxy binary %s: %w", name, err)
+	}
+
+	if info.State == AliasStale {
+		fmt.Printf("Refreshed global alias: %s\n", name)
+	} else {
+		fmt.Printf("Created global alias: %s\n", name)
+	}
+	if info.ShadowedBy != "" {
+		fmt.Printf("Warning: %s is shadowed on PATH by %s\n", name, info.ShadowedBy)
+	}
+	return nil
+}
+
+// RemoveAlias deletes a proxy alias. Foreign binaries are only removed when force is set.
+func RemoveAlias(name string, force bool) error {
+	info, err := InspectAlias(name)
+	if err != nil {
+		return err
+	}
+	switch info.State {
+	case AliasMissing:
+		return fmt.Errorf("alias %s does not exist", name)
+	case AliasForeign:
+		if !force {
+			return fmt.Errorf("%s is not an ezship proxy (use --force to remove it anyway)", info.Path)
+		}
+	}
+	if err := os.Remove(info.Path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", info.Path, err)
+	}
+	return nil
+}
+
+// RepairAliases refreshes every stale proxy copy and returns the names it updated
+func RepairAliases() ([]string, error) {
+	dir, self, err := aliasDir()
+	if err != nil {
+		return nil, err
+	}
+	return repairAliases(AllAliases(LoadConfig()), dir, self)
+}
+
+// repairAliases rewrites the stale proxy copies among names in dir from the binary at selfPath
+func repairAliases(names []string, dir, self string) ([]string, error) {
+	var repaired []string
+	var errs []string
+	for _, name := range names {
+		info := inspectAlias(name, dir, self)
+		if info.State != AliasStale {
+			continue
+		}
+		if err := writeProxyBinary(self, info.Path); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		repaired = append(repaired, name)
+	}
+
+	if len(errs) > 0 {
+		return repaired, fmt.Errorf("failed to repair aliases: %s", strings.Join(errs, "; "))
+	}
+	return repaired, nil
+}
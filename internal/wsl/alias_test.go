@@ -0,0 +1,117 @@
+package wsl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInspectAlias(t *testing.T) {
+	self, err := os.Executable()
+	if err != nil {
+		t.Skip("cannot locate test binary")
+	}
+	dir := t.TempDir()
+
+	// Missing alias
+	if info := inspectAlias("docker", dir, self); info.State != AliasMissing {
+		t.Errorf("Expected missing alias, got %s", info.State)
+	}
+
+	// A binary that is not ezship
+	os.WriteFile(filepath.Join(dir, "podman.exe"), []byte("not ezship"), 0755)
+	if info := inspectAlias("podman", dir, self); info.State != AliasForeign {
+		t.Errorf("Expected foreign alias, got %s", info.State)
+	}
+
+	// An exact copy of the running binary
+	if err := writeProxyBinary(self, filepath.Join(dir, "k3d.exe")); err != nil {
+		t.Fatalf("writeProxyBinary failed: %v", err)
+	}
+	if info := inspectAlias("k3d", dir, self); info.State != AliasCurrent {
+		t.Errorf("Expected current alias, got %s", info.State)
+	}
+}
+
+func TestRepairStaleAlias(t *testing.T) {
+	self, err := os.Executable()
+	if err != nil {
+		t.Skip("cannot locate test binary")
+	}
+	dir := t.TempDir()
+
+	// An older ezship build: still our module, but not byte-identical to the running binary
+	data, err := os.ReadFile(self)
+	if err != nil {
+		t.Fatalf("failed to read test binary: %v", err)
+	}
+	older := append(append([]byte{}, data...), []byte("older build")...)
+	if err := os.WriteFile(filepath.Join(dir, "docker.exe"), older, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "podman.exe"), []byte("not ezship"), 0755)
+
+	if info := inspectAlias("docker", dir, self); info.State != AliasStale {
+		t.Fatalf("Expected stale alias, got %s", info.State)
+	}
+
+	repaired, err := repairAliases([]string{"docker", "podman", "k3d"}, dir, self)
+	if err != nil {
+		t.Fatalf("repairAliases failed: %v", err)
+	}
+	if len(repaired) != 1 || repaired[0] != "docker" {
+		t.Errorf("repaired = %v; want [docker]", repaired)
+	}
+
+	if info := inspectAlias("docker", dir, self); info.State != AliasCurrent {
+		t.Errorf("Expected refreshed alias to be current, got %s", info.State)
+	}
+	if info := inspectAlias("podman", dir, self); info.State != AliasForeign {
+		t.Errorf("Expected foreign alias to be left alone, got %s", info.State)
+	}
+	if info := inspectAlias("k3d", dir, self); info.State != AliasMissing {
+		t.Errorf("Expected missing alias to stay missing, got %s", info.State)
+	}
+}
+
+func TestIsProxyAlias(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	if !IsProxyAlias("docker") {
+		t.Error("Expected docker to be a proxy alias")
+	}
+	if IsProxyAlias("ezship") {
+		t.Error("Expected ezship not to be a proxy alias")
+	}
+}
+
+func TestAllAliases(t *testing.T) {
+	cfg := Config{ExtraAliases: []string{"helm", "Crictl", "docker", "", "ezship"}}
+	aliases := AllAliases(cfg)
+
+	want := append(append([]string{}, ProxyAliases...), "helm", "crictl")
+	if len(aliases) != len(want) {
+		t.Fatalf("AllAliases = %v; want %v", aliases, want)
+	}
+	for i := range want {
+		if aliases[i] != want[i] {
+			t.Errorf("AllAliases[%d] = %s; want %s", i, aliases[i], want[i])
+		}
+	}
+}
+
+func TestToolEngine(t *testing.T) {
+	cfg := Config{ToolEngines: map[string]string{"mycli": "podman", "helm": ""}}
+	tests := []struct{ tool, want string }{
+		{"docker", "docker"},
+		{"kubectl", "kubectl"},
+		{"crictl", "k3s"},
+		{"skopeo", ""},
+		{"mycli", "podman"},
+		{"helm", ""},
+	}
+	for _, tt := range tests {
+		if got := ToolEngine(tt.tool, cfg); got != tt.want {
+			t.Errorf("ToolEngine(%s) = %q; want %q", tt.tool, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package wsl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxAuditLogSize is the size at which the audit log is rotated to audit.jsonl.1
+const maxAuditLogSize = 10 << 20
+
+const redacted = "***"
+
+// AuditEntry is one line of the JSONL audit log
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Engine     string    `json:"engine"`
+	Args       []string  `json:"args"`
+	Dir        string    `json:"dir"`
+	ExitCode   int       `json:"exit_code"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// AuditQuery filters audit entries for 'ezship history'
+type AuditQuery struct {
+	Engine     string
+	Contains   string
+	FailedOnly bool
+	Limit      int
+}
+
+// secretFlags have values that are redacted entirely
+var secretFlags = map[string]bool{
+	"--password": true, "--token": true, "--docker-password": true, "--client-secret": true,
+}
+
+// secretKeyValueFlags have KEY=VALUE values whose VALUE is redacted
+var secretKeyValueFlags = map[string]bool{
+	"-e": true, "--env": true, "--build-arg": true, "--from-literal": true,
+	"--set": true, "--set-string": true,
+}
+
+// GetAuditLogPath returns the audit log location (audit_log_path in the config, or the default)
+func GetAuditLogPath(cfg Config) string {
+	if cfg.AuditLogPath != "" {
+		return cfg.AuditLogPath
+	}
+	appData := os.Getenv("APPDATA")
+	return filepath.Join(appData, "ezship", "audit.jsonl")
+}
+
+func redactKeyValue(v string) string {
+	if key, _, ok := strings.Cut(v, "="); ok {
+		return key + "=" + redacted
+	}
+	return v // "-e KEY" forwards the caller's variable without exposing it
+}
+
+// isLoginCommand reports whether the subcommand is 'login'. It is the first positional
+// argument ('docker login'), or the second after a global flag's value or a command group
+// ('docker --config dir login', 'helm registry login'); a later "login" is an image or argument.
+func isLoginCommand(args []string) bool {
+	positional := 0
+	for _, a := range args {
+		if strings.HasPrefix(a, "-") {
+			continue
+		}
+		if a == "login" {
+			return true
+		}
+		if positional++; positional == 2 {
+			return false
+		}
+	}
+	return false
+}
+
+// RedactArgs masks secrets in command arguments: --password/--token values, "-p" for
+// 'login', and the values of -e/--env/--build-arg KEY=VALUE pairs.
+func RedactArgs(args []string) []string {
+	out := make([]string, len(args))
+	copy(out, args)
+
+	isLogin := isLoginCommand(args)
+
+	for i := 0; i < len(out); i++ {
+		name, value, hasValue := strings.Cut(out[i], "=")
+		secret := secretFlags[name] || (isLogin && name == "-p")
+		switch {
+		case secret && hasValue:
+			out[i] = name + "=" + redacted
+		case secret && i+1 < len(out):
+			i++
+			out[i] = redacted
+		case secretKeyValueFlags[name] && hasValue:
+			out[i] = name + "=" + redactKeyValue(value)
+		case secretKeyValueFlags[name] && i+1 < len(out):
+			i++
+			out[i] = redactKeyValue(out[i])
+		}
+	}
+	return out
+}
+
+// exitCode extracts the process exit code from a command error
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// WriteAuditEntry appends an entry to the audit log, rotating it when it grows too large
+func WriteAuditEntry(cfg Config, entry AuditEntry) error {
+	path := GetAuditLogPath(cfg)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() > maxAuditLogSize {
+		os.Rename(path, path+".1")
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// A single write per entry keeps lines intact when several proxies log at once
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// ReadAuditLog returns the entries matching the query, newest first
+func ReadAuditLog(cfg Config, q AuditQuery) ([]AuditEntry, error) {
+	f, err := os.Open(GetAuditLogPath(cfg))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var e AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue // skip damaged lines
+		}
+		if q.Matches(e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Newest first, then apply the limit
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[:q.Limit]
+	}
+	return entries, nil
+}
+
+// Matches reports whether an entry satisfies the query
+func (q AuditQuery) Matches(e AuditEntry) bool {
+	if q.Engine != "" && !strings.EqualFold(q.Engine, e.Engine) {
+		return false
+	}
+	if q.FailedOnly && e.ExitCode == 0 {
+		return false
+	}
+	if q.Contains != "" {
+		line := strings.ToLower(e.Engine + " " + strings.Join(e.Args, " ") + " " + e.Dir)
+		if !strings.Contains(line, strings.ToLower(q.Contains)) {
+			return false
+		}
+	}
+	return true
+}
+
+// CommandLine renders the entry's command for display
+func (e AuditEntry) CommandLine() string {
+	return strings.TrimSpace(e.Engine + " " + strings.Join(e.Args, " "))
+}
@@ -0,0 +1,63 @@
+package wsl
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactArgs(t *testing.T) {
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"login -u me -p hunter2 registry.io", "login -u me -p *** registry.io"},
+		{"login --password=hunter2", "login --password=***"},
+		{"registry login -p hunter2 ghcr.io", "registry login -p *** ghcr.io"},
+		{"run -p 8080:80 img login", "run -p 8080:80 img login"},
+		{"run -p 8080:80 -e DB_PASS=secret -e HOME nginx", "run -p 8080:80 -e DB_PASS=*** -e HOME nginx"},
+		{"build --build-arg TOKEN=abc --env=A=B .", "build --build-arg TOKEN=*** --env=A=*** ."},
+		{"get pods --token xyz", "get pods --token ***"},
+		{"create secret generic s --from-literal=pw=123", "create secret generic s --from-literal=pw=***"},
+	}
+
+	for _, tt := range tests {
+		got := strings.Join(RedactArgs(strings.Fields(tt.args)), " ")
+		if got != tt.want {
+			t.Errorf("RedactArgs(%q) = %q; want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestAuditLogRoundTrip(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	cfg := Config{AuditLog: true}
+
+	base := time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	WriteAuditEntry(cfg, AuditEntry{Time: base, Engine: "docker", Args: []string{"ps"}, ExitCode: 0})
+	WriteAuditEntry(cfg, AuditEntry{Time: base.Add(time.Minute), Engine: "kubectl", Args: []string{"get", "pods"}, ExitCode: 1})
+	WriteAuditEntry(cfg, AuditEntry{Time: base.Add(2 * time.Minute), Engine: "docker", Args: []string{"build", "."}, ExitCode: 0})
+
+	all, err := ReadAuditLog(cfg, AuditQuery{})
+	if err != nil {
+		t.Fatalf("ReadAuditLog failed: %v", err)
+	}
+	if len(all) != 3 || all[0].CommandLine() != "docker build ." {
+		t.Fatalf("Expected 3 entries newest first, got %v", all)
+	}
+
+	docker, _ := ReadAuditLog(cfg, AuditQuery{Engine: "docker", Limit: 1})
+	if len(docker) != 1 || docker[0].CommandLine() != "docker build ." {
+		t.Errorf("Unexpected engine filter result: %v", docker)
+	}
+
+	failed, _ := ReadAuditLog(cfg, AuditQuery{FailedOnly: true})
+	if len(failed) != 1 || failed[0].Engine != "kubectl" {
+		t.Errorf("Unexpected failed filter result: %v", failed)
+	}
+
+	search, _ := ReadAuditLog(cfg, AuditQuery{Contains: "PODS"})
+	if len(search) != 1 {
+		t.Errorf("Expected case-insensitive search to find 1 entry, got %d", len(search))
+	}
+}
@@ -0,0 +1,310 @@
+package wsl
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultBackupRetention is how many backups per profile are kept when backup_retention is unset
+const DefaultBackupRetention = 5
+
+const backupExt = ".tar.gz"
+
+var backupNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// BackupInfo is the metadata stored next to a backup archive
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Profile   string    `json:"profile"`
+	Distro    string    `json:"distro"`
+	Version   string    `json:"ezship_version"`
+	BaseImage string    `json:"base_image"`
+	Engines   []string  `json:"engines"`
+	Size      int64     `json:"size"`
+	Created   time.Time `json:"created"`
+	SHA256    string    `json:"sha256,omitempty"`
+}
+
+// installedEngines lists the engines installed in the active distro. Tests swap it out.
+var installedEngines = func() []string {
+	var engines []string
+	for _, e := range GetAllEnginesStatus() {
+		if e.Version != "Not Installed" {
+			engines = append(engines, e.Name)
+		}
+	}
+	return engines
+}
+
+// GetBackupDir returns the backup directory (backup_dir in the config, or the default)
+func GetBackupDir(cfg Config) string {
+	if cfg.BackupDir != "" {
+		return cfg.BackupDir
+	}
+	appData := os.Getenv("APPDATA")
+	return filepath.Join(appData, "ezship", "backups")
+}
+
+// backupRetention returns how many backups per profile to keep (0 keeps all)
+func backupRetention(cfg Config) int {
+	switch {
+	case cfg.BackupRetention == 0:
+		return DefaultBackupRetention
+	case cfg.BackupRetention < 0:
+		return 0
+	}
+	return cfg.BackupRetention
+}
+
+// ArchivePath returns the location of the backup's archive
+func (b BackupInfo) ArchivePath(dir string) string {
+	return filepath.Join(dir, b.Name+backupExt)
+}
+
+// FormatSize renders a byte count for humans (e.g. "1.4 GB")
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// CreateBackup stops the active distro and exports it to a compressed archive in the backup dir.
+// An empty name generates one from the profile and the time.
+func CreateBackup(name string) (*BackupInfo, error) {
+	var info *BackupInfo
+	err := withLock(OperationLock, "backing up distro", func() error {
+		var err error
+		info, err = createBackup(name)
+		return err
+	})
+	return info, err
+}
+
+func createBackup(name string) (*BackupInfo, error) {
+	cfg := LoadConfig()
+	dir := GetBackupDir(cfg)
+
+	if name == "" {
+		name = activeProfile + "-" + time.Now().Format("20060102-150405")
+	}
+	info, err := exportBackup(cfg, dir, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBackupInfo(dir, info); err != nil {
+		os.Remove(info.ArchivePath(dir))
+		return nil, err
+	}
+
+	rotateBackups(dir, activeProfile, backupRetention(cfg))
+	return info, nil
+}
+
+// exportBackup stops the active distro and exports it to <dir>/<name>.tar.gz
+func exportBackup(cfg Config, dir, name string) (*BackupInfo, error) {
+	if !backupNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid backup name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	if installed, _ := IsDistroInstalled(); !installed {
+		return nil, fmt.Errorf("distro %s is not installed", DistroName)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	info := &BackupInfo{
+		Name:      name,
+		Profile:   activeProfile,
+		Distro:    DistroName,
+		Version:   Version,
+		BaseImage: CurrentBaseImage(cfg).Name,
+		Engines:   installedEngines(),
+		Created:   time.Now().UTC(),
+	}
+	archive := info.ArchivePath(dir)
+	if _, err := os.Stat(archive); err == nil {
+		return nil, fmt.Errorf("backup %q already exists", name)
+	}
+
+	// Stop the engines so the export is consistent
+	InvalidateEngineReady("")
+	wslCommand("--terminate", DistroName).Run()
+
+	sha, err := exportDistro(archive)
+	if err != nil {
+		return nil, err
+	}
+	info.SHA256 = sha
+
+	stat, err := os.Stat(archive)
+	if err != nil {
+		return nil, err
+	}
+	info.Size = stat.Size()
+	return info, nil
+}
+
+// exportDistro streams 'wsl --export' through gzip into archive, via a .part file,
+// and returns the archive's SHA256
+func exportDistro(archive string) (string, error) {
+	part := archive + ".part"
+	f, err := os.Create(part)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(part)
+
+	// Hash while writing, so integrity checks cost no extra pass over the archive
+	h := sha256.New()
+	// Speed matters more than ratio: container layers are mostly compressed already
+	zw, _ := gzip.NewWriterLevel(io.MultiWriter(f, h), gzip.BestSpeed)
+	var stderr strings.Builder
+	cmd := wslCommand("--export", DistroName, "-")
+	cmd.Stdout = zw
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+	closeErr := zw.Close()
+	if err := f.Close(); err != nil && closeErr == nil {
+		closeErr = err
+	}
+	if runErr != nil {
+		return "", fmt.Errorf("failed to export distro: %s (%w)", strings.TrimSpace(stderr.String()), runErr)
+	}
+	if closeErr != nil {
+		return "", fmt.Errorf("failed to write backup: %w", closeErr)
+	}
+	if err := os.Rename(part, archive); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func writeBackupInfo(dir string, info *BackupInfo) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, info.Name+".json"), data, 0644)
+}
+
+// ListBackups returns the backups in the backup dir, newest first
+func ListBackups(cfg Config) ([]BackupInfo, error) {
+	return listBackups(GetBackupDir(cfg))
+}
+
+func listBackups(dir string) ([]BackupInfo, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var backups []BackupInfo
+	for _, m := range matches {
+		data, err := os.ReadFile(m)
+		if err != nil {
+			continue
+		}
+		var info BackupInfo
+		if err := json.Unmarshal(data, &info); err != nil || info.Name == "" {
+			continue // not ours
+		}
+		backups = append(backups, info)
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Created.After(backups[j].Created) })
+	return backups, nil
+}
+
+// FindBackup looks up a backup by name
+func FindBackup(cfg Config, name string) (*BackupInfo, error) {
+	backups, err := ListBackups(cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range backups {
+		if b.Name == name {
+			return &b, nil
+		}
+	}
+	return nil, fmt.Errorf("backup %q not found in %s", name, GetBackupDir(cfg))
+}
+
+// rotateBackups deletes the oldest backups of a profile beyond keep (0 keeps all)
+func rotateBackups(dir, profile string, keep int) {
+	if keep <= 0 {
+		return
+	}
+	backups, err := listBackups(dir)
+	if err != nil {
+		return
+	}
+	n := 0
+	for _, b := range backups {
+		if b.Profile != profile {
+			continue
+		}
+		n++
+		if n > keep {
+			removeBackup(dir, b)
+		}
+	}
+}
+
+func removeBackup(dir string, b BackupInfo) {
+	os.Remove(b.ArchivePath(dir))
+	os.Remove(filepath.Join(dir, b.Name+".json"))
+}
+
+// RestoreBackup re-imports a backup as the active profile's distro. An existing distro is only
+// replaced with force.
+func RestoreBackup(name string, force bool) error {
+	return withLock(OperationLock, "restoring backup "+name, func() error {
+		return restoreBackup(name, force)
+	})
+}
+
+func restoreBackup(name string, force bool) error {
+	cfg := LoadConfig()
+	info, err := FindBackup(cfg, name)
+	if err != nil {
+		return err
+	}
+	archive := info.ArchivePath(GetBackupDir(cfg))
+	if _, err := os.Stat(archive); err != nil {
+		return fmt.Errorf("backup archive missing: %w", err)
+	}
+	if info.SHA256 != "" && !verifyFile(archive, info.SHA256) {
+		return fmt.Errorf("backup %q is corrupt (checksum mismatch)", name)
+	}
+
+	if installed, _ := IsDistroInstalled(); installed {
+		if !force {
+			return fmt.Errorf("distro %s already exists; use --force to replace it with the backup", DistroName)
+		}
+		if err := ResetDistro(); err != nil {
+			return err
+		}
+	}
+
+	installDir := GetInstallDir()
+	base, err := GetBaseImage(info.BaseImage)
+	if err != nil {
+		base = CurrentBaseImage(cfg)
+	}
+	return importDistro(installDir, archive, base)
+}
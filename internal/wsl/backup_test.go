@@ -0,0 +1,134 @@
+package wsl
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// distroResponses fake 'wsl --list' printing distros and 'wsl --export' printing a rootfs
+func distroResponses(distros string) []stubResponse {
+	return []stubResponse{
+		{match: "--list", output: distros},
+		{match: "--export", reply: func(args []string) string { return "rootfs of " + args[1] }},
+	}
+}
+
+// stubDistroBackend stubs wsl.exe with distroResponses and docker as the installed engine
+func stubDistroBackend(t *testing.T, distros string) *wslRecorder {
+	t.Helper()
+	calls := stubWSL(t, distroResponses(distros)...)
+	orig := installedEngines
+	installedEngines = func() []string { return []string{"docker"} }
+	t.Cleanup(func() { installedEngines = orig })
+	return calls
+}
+
+func TestCreateBackup(t *testing.T) {
+	stubDistroBackend(t, "Ubuntu\r\nezship\r\n")
+
+	info, err := CreateBackup("before-upgrade")
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	if info.Distro != "ezship" || info.Profile != DefaultProfile || len(info.Engines) != 1 || info.Size == 0 {
+		t.Errorf("Unexpected backup metadata: %+v", info)
+	}
+
+	f, err := os.Open(info.ArchivePath(GetBackupDir(LoadConfig())))
+	if err != nil {
+		t.Fatalf("Backup archive missing: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Backup is not gzip compressed: %v", err)
+	}
+	data, _ := io.ReadAll(zr)
+	if string(data) != "rootfs of ezship" {
+		t.Errorf("Unexpected archive content %q", data)
+	}
+
+	if _, err := CreateBackup("before-upgrade"); err == nil {
+		t.Error("Expected error when a backup name is reused")
+	}
+	if _, err := CreateBackup("../escape"); err == nil {
+		t.Error("Expected error for a backup name with a path")
+	}
+}
+
+func TestCreateBackupNotInstalled(t *testing.T) {
+	stubDistroBackend(t, "ezship-work\r\n")
+	if _, err := CreateBackup(""); err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Errorf("Expected not installed error, got %v", err)
+	}
+}
+
+func TestRotateBackups(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i, profile := range []string{"default", "default", "work", "default"} {
+		info := &BackupInfo{Name: "b" + string(rune('0'+i)), Profile: profile, Created: now.Add(time.Duration(i) * time.Minute)}
+		os.WriteFile(info.ArchivePath(dir), []byte("x"), 0644)
+		writeBackupInfo(dir, info)
+	}
+
+	rotateBackups(dir, "default", 2)
+
+	backups, _ := listBackups(dir)
+	var names []string
+	for _, b := range backups {
+		names = append(names, b.Name)
+	}
+	if got := strings.Join(names, ","); got != "b3,b2,b1" {
+		t.Errorf("Expected the oldest default backup to be rotated, got %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b0"+backupExt)); !os.IsNotExist(err) {
+		t.Error("Expected the rotated archive to be deleted")
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	calls := stubDistroBackend(t, "ezship\r\n")
+	if _, err := CreateBackup("snap"); err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+
+	if err := RestoreBackup("snap", false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("Expected restore over an existing distro to require --force, got %v", err)
+	}
+	if err := RestoreBackup("missing", true); err == nil {
+		t.Error("Expected error for an unknown backup")
+	}
+
+	// Once the distro is gone the backup is imported in place
+	wslList := wslCommand
+	wslCommand = func(args ...string) *exec.Cmd {
+		if args[0] == "--list" {
+			return stubCommand("")
+		}
+		return wslList(args...)
+	}
+	if err := RestoreBackup("snap", false); err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+	args := calls.Args()
+	last := args[len(args)-1]
+	if last[0] != "--import" || last[1] != "ezship" || !strings.HasSuffix(last[3], "snap"+backupExt) {
+		t.Errorf("Unexpected import call: %v", last)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{512: "512 B", 1536: "1.5 KB", 3 << 30: "3.0 GB"}
+	for n, want := range tests {
+		if got := FormatSize(n); got != want {
+			t.Errorf("FormatSize(%d) = %s; want %s", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package wsl
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultBaseImage is used when the config does not name a base image
+const DefaultBaseImage = "ubuntu-24.04"
+
+// BaseImage is a root filesystem the ezship distro can be created from
+type BaseImage struct {
+	Name           string
+	Title          string
+	URL            string
+	SumsURL        string // SHA256SUMS (or <file>.sha256) location, "" if none is published
+	PackageManager string // key into packageManagers
+}
+
+// BaseImages are the selectable base distributions
+var BaseImages = []BaseImage{
+	{
+		Name:           "ubuntu-24.04",
+		Title:          "Ubuntu 24.04 LTS (minimal)",
+		URL:            UbuntuURL,
+		SumsURL:        checksumURL(UbuntuURL),
+		PackageManager: "apt",
+	},
+	{
+		Name:           "ubuntu-22.04",
+		Title:          "Ubuntu 22.04 LTS (minimal)",
+		URL:            Ubuntu2204URL,
+		SumsURL:        checksumURL(Ubuntu2204URL),
+		PackageManager: "apt",
+	},
+	{
+		Name:           "debian-12",
+		Title:          "Debian 12 (bookworm)",
+		URL:            Debian12URL,
+		PackageManager: "apt",
+	},
+	{
+		Name:           "alpine",
+		Title:          "Alpine Linux 3.20 (smallest footprint)",
+		URL:            AlpineURL,
+		SumsURL:        AlpineURL + ".sha256",
+		PackageManager: "apk",
+	},
+}
+
+// checkVerifiable refuses to download a base image that publishes no checksum: ezship would
+// import whatever the server sent. Such images are only used from an explicit --rootfs.
+func (b BaseImage) checkVerifiable() error {
+	if b.SumsURL == "" {
+		return fmt.Errorf("%s publishes no checksum, so ezship does not download it: fetch %s yourself, check it, "+
+			"and pass it with 'ezship setup --base %s --rootfs <file>'", b.Title, b.URL, b.Name)
+	}
+	return nil
+}
+
+// packageManager describes how a base image installs engine packages
+type packageManager struct {
+	install  string              // installs packages from the network (package names are appended)
+	local    string              // installs the package files in a directory (%s is the quoted directory)
+	fetch    string              // downloads packages with dependencies into the current directory (%s is the package list)
+	dir      string              // bundle directory holding the package files
+	addUser  string              // creates a user in the docker group unless it exists (%[1]s is the name)
+	packages map[string][]string // engine -> packages
+}
+
+var packageManagers = map[string]packageManager{
+	"apt": {
+		install: "apt-get update && apt-get install -y",
+		local:   "apt-get install -y --no-install-recommends %s/*.deb",
+		// apt-get download fetches packages even if they are already installed locally
+		fetch: "apt-get update -qq && apt-get download $(apt-cache depends --recurse --no-recommends --no-suggests " +
+			"--no-conflicts --no-breaks --no-replaces --no-enhances %s | grep '^\\w' | sort -u)",
+		dir: "debs",
+		addUser: "(grep -q '^docker:' /etc/group || groupadd -r docker) && (id -u %[1]s >/dev/null 2>&1 || useradd -m -s /bin/bash %[1]s)" +
+			" && usermod -aG docker %[1]s",
+		packages: map[string][]string{
+			"docker":  {"docker.io"},
+			"podman":  {"podman", "uidmap"},
+			"nerdctl": {"containerd"},
+		},
+	},
+	"apk": {
+		install: "apk add --no-cache",
+		local:   "apk add --no-cache --no-network %s/*.apk",
+		fetch:   "apk update -q && apk fetch -R %s",
+		dir:     "apks",
+		addUser: "(grep -q '^docker:' /etc/group || addgroup -S docker) && (id -u %[1]s >/dev/null 2>&1 || adduser -D -s /bin/sh %[1]s)" +
+			" && addgroup %[1]s docker",
+		packages: map[string][]string{
+			"docker":  {"docker"},
+			"podman":  {"podman", "shadow-uidmap"},
+			"nerdctl": {"containerd"},
+		},
+	},
+}
+
+// GetBaseImage looks up a base image by name ("" is the default)
+func GetBaseImage(name string) (BaseImage, error) {
+	if name == "" {
+		name = DefaultBaseImage
+	}
+	for _, b := range BaseImages {
+		if strings.EqualFold(b.Name, name) {
+			return b, nil
+		}
+	}
+	return BaseImage{}, fmt.Errorf("unknown base image %q (available: %s)", name, strings.Join(BaseImageNames(), ", "))
+}
+
+// BaseImageNames returns the names of the selectable base images, sorted
+func BaseImageNames() []string {
+	names := make([]string, len(BaseImages))
+	for i, b := range BaseImages {
+		names[i] = b.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CurrentBaseImage returns the base image the config records for the active profile, falling back to the default
+func CurrentBaseImage(cfg Config) BaseImage {
+	b, err := GetBaseImage(cfg.profileBaseImage())
+	if err != nil {
+		b, _ = GetBaseImage(DefaultBaseImage)
+	}
+	return b
+}
+
+// rootfsFile is the name the downloaded rootfs is stored under in the install directory
+func (b BaseImage) rootfsFile() string {
+	if b.Name == DefaultBaseImage {
+		return "ubuntu-rootfs.tar.xz" // name used before other bases existed
+	}
+	ext := ".tar.gz"
+	if strings.HasSuffix(b.URL, ".tar.xz") {
+		ext = ".tar.xz"
+	}
+	return b.Name + "-rootfs" + ext
+}
+
+// packageManager returns the package manager logic of the base image
+func (b BaseImage) packageManager() packageManager {
+	return packageManagers[b.PackageManager]
+}
+
+// installPackagesCommand returns the shell command installing packages from the network
+func (pm packageManager) installPackagesCommand(pkgs ...string) string {
+	return pm.install + " " + strings.Join(pkgs, " ")
+}
+
+// onlineInstallCommand returns the shell command installing engine from the internet
+func (b BaseImage) onlineInstallCommand(engine string) (string, error) {
+	pm := b.packageManager()
+	switch engine {
+	case "docker", "podman":
+		return pm.installPackagesCommand(pm.packages[engine]...), nil
+	case "k3s":
+		return pm.installPackagesCommand("curl") + " && curl -sfL " + K3sInstallURL + " | INSTALL_K3S_SKIP_ENABLE=true sh -", nil
+	case "nerdctl":
+		pkgs := append(append([]string{}, pm.packages[engine]...), "wget", "tar")
+		return pm.installPackagesCommand(pkgs...) + " && wget -q " + NerdctlURL + " -O /tmp/" + path.Base(NerdctlURL) +
+			" && tar -C /usr/local/bin -xzf /tmp/" + path.Base(NerdctlURL), nil
+	case "k3d":
+		return pm.installPackagesCommand("curl", "bash") + " && curl -s " + K3dInstallURL + " | bash", nil
+	}
+	return "", fmt.Errorf("unknown engine: %s", engine)
+}
@@ -0,0 +1,65 @@
+package wsl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetBaseImage(t *testing.T) {
+	b, err := GetBaseImage("")
+	if err != nil || b.Name != DefaultBaseImage {
+		t.Fatalf("GetBaseImage(\"\") = %+v, %v; want the default", b, err)
+	}
+	if b.rootfsFile() != "ubuntu-rootfs.tar.xz" {
+		t.Errorf("Expected the default image to keep its rootfs name, got %s", b.rootfsFile())
+	}
+
+	b, err = GetBaseImage("Alpine")
+	if err != nil || b.PackageManager != "apk" || b.rootfsFile() != "alpine-rootfs.tar.gz" {
+		t.Errorf("GetBaseImage(Alpine) = %+v, %v", b, err)
+	}
+
+	if _, err := GetBaseImage("fedora"); err == nil || !strings.Contains(err.Error(), "debian-12") {
+		t.Errorf("Expected unknown base image error listing the choices, got %v", err)
+	}
+
+	if got := CurrentBaseImage(Config{BaseImage: "bogus"}); got.Name != DefaultBaseImage {
+		t.Errorf("Expected invalid config value to fall back to the default, got %s", got.Name)
+	}
+}
+
+func TestOnlineInstallCommand(t *testing.T) {
+	tests := []struct {
+		base, engine, want string
+	}{
+		{"ubuntu-24.04", "docker", "apt-get update && apt-get install -y docker.io"},
+		{"debian-12", "podman", "apt-get update && apt-get install -y podman uidmap"},
+		{"alpine", "docker", "apk add --no-cache docker"},
+		{"alpine", "k3d", "apk add --no-cache curl bash && curl -s " + K3dInstallURL + " | bash"},
+	}
+	for _, tt := range tests {
+		b, _ := GetBaseImage(tt.base)
+		got, err := b.onlineInstallCommand(tt.engine)
+		if err != nil || got != tt.want {
+			t.Errorf("%s onlineInstallCommand(%s) = %q, %v; want %q", tt.base, tt.engine, got, err, tt.want)
+		}
+	}
+
+	b, _ := GetBaseImage("alpine")
+	if _, err := b.onlineInstallCommand("lxd"); err == nil {
+		t.Error("Expected error for unknown engine")
+	}
+}
+
+func TestCheckVerifiable(t *testing.T) {
+	for _, b := range BaseImages {
+		err := b.checkVerifiable()
+		if (b.SumsURL == "") != (err != nil) {
+			t.Errorf("%s: checkVerifiable() = %v", b.Name, err)
+		}
+	}
+	debian, _ := GetBaseImage("debian-12")
+	if err := debian.checkVerifiable(); err == nil || !strings.Contains(err.Error(), "--rootfs") {
+		t.Errorf("Expected debian-12 to require --rootfs, got %v", err)
+	}
+}
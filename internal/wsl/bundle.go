@@ -0,0 +1,320 @@
+package wsl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Artifact locations used by online installs and 'ezship bundle create'
+const (
+	NerdctlURL    = "https://github.com/containerd/nerdctl/releases/download/v1.7.3/nerdctl-1.7.3-linux-amd64.tar.gz"
+	K3sInstallURL = "https://get.k3s.io"
+	K3sLatestURL  = "https://github.com/k3s-io/k3s/releases/latest"
+	K3sImagesName = "k3s-airgap-images-amd64.tar.zst"
+	K3sSumsName   = "sha256sum-amd64.txt"
+	K3dBinaryURL  = "https://github.com/k3d-io/k3d/releases/latest/download/k3d-linux-amd64"
+	K3dInstallURL = "https://raw.githubusercontent.com/k3d-io/k3d/main/install.sh"
+)
+
+const bundleManifestName = "manifest.json"
+
+// BundleEngines are the engines an artifact bundle can hold
+var BundleEngines = []string{"docker", "podman", "k3s", "nerdctl", "k3d"}
+
+// BundleManifest describes an artifact bundle / cache directory
+type BundleManifest struct {
+	Created time.Time         `json:"created"`
+	Version string            `json:"ezship_version"`
+	Base    string            `json:"base_image,omitempty"` // base image the packages were resolved for
+	Rootfs  string            `json:"rootfs,omitempty"`     // relative path of the rootfs tarball
+	Engines []string          `json:"engines"`
+	Files   map[string]string `json:"files"` // relative path (forward slashes) -> sha256
+}
+
+// GetCacheDir returns the engine artifact cache (artifact_cache in the config, or the default)
+func GetCacheDir(cfg Config) string {
+	if cfg.ArtifactCache != "" {
+		return cfg.ArtifactCache
+	}
+	appData := os.Getenv("APPDATA")
+	return filepath.Join(appData, "ezship", "cache")
+}
+
+// LoadBundleManifest reads the manifest of a bundle directory
+func LoadBundleManifest(dir string) (*BundleManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, bundleManifestName))
+	if err != nil {
+		return nil, err
+	}
+	var m BundleManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// HasEngine reports whether the bundle holds artifacts for engine
+func (m *BundleManifest) HasEngine(engine string) bool {
+	for _, e := range m.Engines {
+		if e == engine {
+			return true
+		}
+	}
+	return false
+}
+
+// BaseImage returns the base image the bundle was built for
+func (m *BundleManifest) BaseImage() BaseImage {
+	b, err := GetBaseImage(m.Base)
+	if err != nil {
+		return BaseImage{Name: m.Base}
+	}
+	return b
+}
+
+// engineArtifactPrefixes returns the bundle paths holding an engine's artifacts
+func engineArtifactPrefixes(engine string, base BaseImage) []string {
+	pkgDir := base.packageManager().dir + "/" + engine + "/"
+	switch engine {
+	case "k3s", "k3d":
+		return []string{engine + "/"}
+	case "nerdctl":
+		return []string{pkgDir, "nerdctl/"}
+	}
+	return []string{pkgDir}
+}
+
+// Verify checks the checksums of the bundle files under the given prefixes (all files if none)
+func (m *BundleManifest) Verify(dir string, prefixes ...string) error {
+	var bad []string
+	for rel, sha := range m.Files {
+		if !hasAnyPrefix(rel, prefixes) {
+			continue
+		}
+		if !verifyFile(filepath.Join(dir, filepath.FromSlash(rel)), sha) {
+			bad = append(bad, rel)
+		}
+	}
+	if len(bad) > 0 {
+		sort.Strings(bad)
+		return fmt.Errorf("bundle files missing or corrupt: %s", strings.Join(bad, ", "))
+	}
+	return nil
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeBundleManifest hashes every file in dir and writes the manifest
+func writeBundleManifest(dir string, m *BundleManifest) error {
+	m.Files = make(map[string]string)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == bundleManifestName || strings.HasSuffix(rel, ".part") {
+			return nil
+		}
+		sha, err := fileHash(p)
+		if err != nil {
+			return err
+		}
+		m.Files[rel] = sha
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, bundleManifestName), data, 0644)
+}
+
+// shellQuote quotes s for sh -c
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// offlineInstallCommand returns the shell command installing engine from a bundle at dir
+func offlineInstallCommand(engine, dir string, base BaseImage) string {
+	root := TranslatePath(dir)
+	pm := base.packageManager()
+	pkgs := fmt.Sprintf(pm.local, shellQuote(root+"/"+pm.dir+"/"+engine))
+	switch engine {
+	case "docker", "podman":
+		return pkgs
+	case "nerdctl":
+		return pkgs +
+			" && tar -C /usr/local/bin -xzf " + shellQuote(root+"/nerdctl/"+path.Base(NerdctlURL))
+	case "k3s":
+		k3s := root + "/k3s/"
+		return "install -m 755 " + shellQuote(k3s+"k3s") + " /usr/local/bin/k3s" +
+			" && mkdir -p /var/lib/rancher/k3s/agent/images" +
+			" && cp " + shellQuote(k3s+K3sImagesName) + " /var/lib/rancher/k3s/agent/images/" +
+			" && INSTALL_K3S_SKIP_DOWNLOAD=true INSTALL_K3S_SKIP_ENABLE=true sh " + shellQuote(k3s+"install.sh")
+	case "k3d":
+		return "install -m 755 " + shellQuote(root+"/k3d/"+path.Base(K3dBinaryURL)) + " /usr/local/bin/k3d"
+	}
+	return ""
+}
+
+// bundleDownload fetches url into dir/sub, verifying it against SHA256SUMS when published
+func bundleDownload(url, dir, sub, name string) error {
+	dest := filepath.Join(dir, sub, name)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	sha, _ := FetchChecksum(checksumURL(url), path.Base(url))
+	return downloadFile(url, dest, sha)
+}
+
+// latestReleaseDownload resolves a GitHub ".../releases/latest" URL to the download URL of the
+// release it points at, so files fetched one after another all come from the same release
+func latestReleaseDownload(latestURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), downloadStallTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, latestURL, nil)
+	if err != nil {
+		return "", err
+	}
+	client := *downloadClient
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	// ".../releases/latest" redirects to ".../releases/tag/<tag>"
+	loc, err := resp.Location()
+	if err != nil {
+		return "", fmt.Errorf("%s did not redirect to a release (%s)", latestURL, resp.Status)
+	}
+	prefix, tag, ok := strings.Cut(loc.String(), "/releases/tag/")
+	if !ok || tag == "" {
+		return "", fmt.Errorf("unexpected release location %s", loc)
+	}
+	return prefix + "/releases/download/" + tag, nil
+}
+
+// bundleK3s downloads the k3s binary and airgap images of the latest release into dir/k3s,
+// verified against the release's checksum list
+func bundleK3s(latestURL, dir string) error {
+	release, err := latestReleaseDownload(latestURL)
+	if err != nil {
+		return fmt.Errorf("failed to find the latest k3s release: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "k3s"), 0755); err != nil {
+		return err
+	}
+	for _, name := range []string{"k3s", K3sImagesName} {
+		sha, err := FetchChecksum(release+"/"+K3sSumsName, name)
+		if err != nil {
+			return err
+		}
+		if err := downloadFile(release+"/"+name, filepath.Join(dir, "k3s", name), sha); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateBundle downloads the rootfs and the artifacts of the given engines into dir, so that
+// 'ezship setup' can run on a machine without internet access. Packages are resolved with the
+// package manager inside the local ezship distro, so it must be set up on this (connected)
+// machine from the same base image as the offline one.
+func CreateBundle(dir string, engines []string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+
+	base := CurrentBaseImage(LoadConfig())
+	pm := base.packageManager()
+	m := &BundleManifest{Created: time.Now().UTC(), Version: Version, Base: base.Name}
+
+	if err := base.checkVerifiable(); err != nil {
+		return err
+	}
+	fmt.Printf("Downloading %s rootfs...\n", base.Title)
+	rootfsName := path.Base(base.URL)
+	sha, _ := FetchChecksum(base.SumsURL, rootfsName)
+	if err := os.MkdirAll(filepath.Join(dir, "rootfs"), 0755); err != nil {
+		return err
+	}
+	if err := downloadFile(base.URL, filepath.Join(dir, "rootfs", rootfsName), sha); err != nil {
+		return fmt.Errorf("failed to download rootfs: %w", err)
+	}
+	m.Rootfs = "rootfs/" + rootfsName
+
+	for _, engine := range engines {
+		fmt.Printf("Collecting artifacts for %s...\n", engine)
+
+		if pkgs, ok := pm.packages[engine]; ok {
+			if err := SetupDistro(); err != nil {
+				return err
+			}
+			pkgDir := filepath.Join(dir, pm.dir, engine)
+			if err := os.MkdirAll(pkgDir, 0755); err != nil {
+				return err
+			}
+			script := "cd " + shellQuote(TranslatePath(pkgDir)) + " && " + fmt.Sprintf(pm.fetch, strings.Join(pkgs, " "))
+			cmd := exec.Command("wsl", "-d", DistroName, "-u", "root", "sh", "-c", script)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				return fmt.Errorf("failed to download packages for %s: %s (%w)", engine, string(output), err)
+			}
+		}
+
+		var err error
+		switch engine {
+		case "k3s":
+			err = bundleK3s(K3sLatestURL, dir)
+			if err == nil {
+				err = bundleDownload(K3sInstallURL, dir, "k3s", "install.sh")
+			}
+		case "nerdctl":
+			err = bundleDownload(NerdctlURL, dir, "nerdctl", path.Base(NerdctlURL))
+		case "k3d":
+			err = bundleDownload(K3dBinaryURL, dir, "k3d", path.Base(K3dBinaryURL))
+		case "docker", "podman":
+		default:
+			err = fmt.Errorf("unknown engine: %s", engine)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to bundle %s: %w", engine, err)
+		}
+		m.Engines = append(m.Engines, engine)
+	}
+
+	if err := writeBundleManifest(dir, m); err != nil {
+		return fmt.Errorf("failed to write bundle manifest: %w", err)
+	}
+	fmt.Printf("Bundle created at %s (%d files)\n", dir, len(m.Files))
+	return nil
+}
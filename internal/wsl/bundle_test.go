@@ -0,0 +1,105 @@
+package wsl
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBundleManifestVerify(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "debs", "docker"), 0755)
+	os.MkdirAll(filepath.Join(dir, "k3s"), 0755)
+	os.WriteFile(filepath.Join(dir, "debs", "docker", "docker.io.deb"), []byte("deb"), 0644)
+	os.WriteFile(filepath.Join(dir, "k3s", "k3s"), []byte("k3s"), 0644)
+
+	base, _ := GetBaseImage(DefaultBaseImage)
+	m := &BundleManifest{Engines: []string{"docker", "k3s"}}
+	if err := writeBundleManifest(dir, m); err != nil {
+		t.Fatalf("writeBundleManifest failed: %v", err)
+	}
+
+	loaded, err := LoadBundleManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadBundleManifest failed: %v", err)
+	}
+	if len(loaded.Files) != 2 || !loaded.HasEngine("k3s") || loaded.HasEngine("podman") {
+		t.Fatalf("Unexpected manifest: %+v", loaded)
+	}
+	if err := loaded.Verify(dir); err != nil {
+		t.Errorf("Expected intact bundle to verify, got %v", err)
+	}
+
+	// Corrupting one engine's files only fails that engine
+	os.WriteFile(filepath.Join(dir, "k3s", "k3s"), []byte("tampered"), 0644)
+	if err := loaded.Verify(dir, engineArtifactPrefixes("k3s", base)...); err == nil {
+		t.Error("Expected tampered k3s binary to fail verification")
+	}
+	if err := loaded.Verify(dir, engineArtifactPrefixes("docker", base)...); err != nil {
+		t.Errorf("Expected docker artifacts to still verify, got %v", err)
+	}
+}
+
+func TestOfflineInstallCommand(t *testing.T) {
+	ubuntu, _ := GetBaseImage(DefaultBaseImage)
+	cmd := offlineInstallCommand("docker", `C:\Users\John Smith\bundle`, ubuntu)
+	want := "apt-get install -y --no-install-recommends '/mnt/c/Users/John Smith/bundle/debs/docker'/*.deb"
+	if cmd != want {
+		t.Errorf("offlineInstallCommand(docker) = %s; want %s", cmd, want)
+	}
+
+	alpine, _ := GetBaseImage("alpine")
+	if got := offlineInstallCommand("podman", `D:\b`, alpine); got != "apk add --no-cache --no-network '/mnt/d/b/apks/podman'/*.apk" {
+		t.Errorf("offlineInstallCommand(podman, alpine) = %s", got)
+	}
+
+	k3s := offlineInstallCommand("k3s", `D:\b`, ubuntu)
+	for _, part := range []string{"/mnt/d/b/k3s/k3s", "INSTALL_K3S_SKIP_DOWNLOAD=true", "/var/lib/rancher/k3s/agent/images"} {
+		if !strings.Contains(k3s, part) {
+			t.Errorf("Expected k3s command to contain %q, got %s", part, k3s)
+		}
+	}
+}
+
+func TestBundleK3s(t *testing.T) {
+	binary, images := []byte("k3s binary"), []byte("airgap images")
+	sums := sha256Hex(binary) + "  k3s\n" + sha256Hex(images) + "  " + K3sImagesName + "\n"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/k3s-io/k3s/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/k3s-io/k3s/releases/tag/v1.30.2+k3s1", http.StatusFound)
+	})
+	mux.HandleFunc("/k3s-io/k3s/releases/download/v1.30.2+k3s1/", func(w http.ResponseWriter, r *http.Request) {
+		switch path.Base(r.URL.Path) {
+		case "k3s":
+			w.Write(binary)
+		case K3sImagesName:
+			w.Write(images)
+		case K3sSumsName:
+			io.WriteString(w, sums)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	if err := bundleK3s(srv.URL+"/k3s-io/k3s/releases/latest", dir); err != nil {
+		t.Fatalf("bundleK3s failed: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "k3s", K3sImagesName)); !bytes.Equal(got, images) {
+		t.Errorf("Unexpected airgap images %q", got)
+	}
+
+	// A file that does not match the release's checksum list is rejected
+	binary = []byte("tampered")
+	if err := bundleK3s(srv.URL+"/k3s-io/k3s/releases/latest", t.TempDir()); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+}
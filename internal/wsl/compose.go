@@ -0,0 +1,186 @@
+package wsl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultComposeFiles are looked up in the working directory when no -f is given, in compose's order
+var defaultComposeFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yml", "docker-compose.yaml"}
+
+var (
+	// composeWinPath matches absolute Windows paths inside a compose file line (C:\data in "C:\data:/data")
+	composeWinPath = regexp.MustCompile(`(^|[\s"'=\[,])([A-Za-z]:\\[^\s"',\]:]*)`)
+	// composeRelPath matches relative paths written with backslashes (.\data, ..\shared\.env)
+	composeRelPath = regexp.MustCompile(`(^|[\s"'=\[,])(\.{1,2}\\[^\s"',\]:]*)`)
+)
+
+// composeRewrite holds the translated compose files created for one proxied invocation
+type composeRewrite struct {
+	dir   string
+	files map[string]string // WSL path of the translated copy -> original Windows path
+}
+
+// TranslateComposeLine rewrites host paths in a single compose file line. Lines are never
+// added or removed, so errors reported against the translated copy keep their line numbers.
+func TranslateComposeLine(line string) string {
+	line = composeWinPath.ReplaceAllStringFunc(line, func(m string) string {
+		sub := composeWinPath.FindStringSubmatch(m)
+		// Double-quoted YAML escapes backslashes ("C:\\data")
+		path := strings.ReplaceAll(sub[2], `\\`, `\`)
+		return sub[1] + TranslatePath(path)
+	})
+	return composeRelPath.ReplaceAllStringFunc(line, func(m string) string {
+		sub := composeRelPath.FindStringSubmatch(m)
+		path := strings.ReplaceAll(sub[2], `\\`, `\`)
+		return sub[1] + strings.ReplaceAll(path, `\`, "/")
+	})
+}
+
+// TranslateComposeFile rewrites the host paths of a whole compose file, line by line
+func TranslateComposeFile(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, l := range lines {
+		lines[i] = TranslateComposeLine(l)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// composeArgsStart returns the index where compose options begin, or -1 if args is not a compose call
+func composeArgsStart(tool string, args []string) int {
+	switch tool {
+	case "docker-compose", "podman-compose":
+		return 0
+	case "docker", "podman", "nerdctl":
+		for i, arg := range args {
+			if arg == "compose" {
+				return i + 1
+			}
+			if !strings.HasPrefix(arg, "-") {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+// prepareCompose translates the compose files used by a compose invocation and points the
+// arguments at the translated copies. It returns args unchanged (and a nil rewrite) when
+// there is nothing to translate.
+func prepareCompose(tool string, args []string) ([]string, *composeRewrite, error) {
+	start := composeArgsStart(tool, args)
+	if start < 0 {
+		return args, nil, nil
+	}
+
+	// Collect the -f/--file values from the compose options (before the subcommand)
+	type fileArg struct {
+		index  int // index of the value in args
+		prefix string
+		path   string
+	}
+	var files []fileArg
+	hasProjectDir := false
+	for i := start; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			break
+		}
+		switch {
+		case (arg == "-f" || arg == "--file") && i+1 < len(args):
+			files = append(files, fileArg{index: i + 1, path: args[i+1]})
+			i++
+		case strings.HasPrefix(arg, "--file="):
+			files = append(files, fileArg{index: i, prefix: "--file=", path: strings.TrimPrefix(arg, "--file=")})
+		case arg == "--project-directory" || strings.HasPrefix(arg, "--project-directory="):
+			hasProjectDir = true
+		}
+	}
+
+	newArgs := append([]string{}, args...)
+	if len(files) == 0 {
+		// COMPOSE_FILE is forwarded and translated by ProxyEnv
+		if os.Getenv("COMPOSE_FILE") != "" {
+			return args, nil, nil
+		}
+		for _, name := range defaultComposeFiles {
+			if _, err := os.Stat(name); err == nil {
+				newArgs = append(newArgs[:start], append([]string{"-f", name}, newArgs[start:]...)...)
+				files = append(files, fileArg{index: start + 1, path: name})
+				break
+			}
+		}
+		if len(files) == 0 {
+			return args, nil, nil
+		}
+		// Compose also merges the matching override file when none are given explicitly
+		base := strings.TrimSuffix(files[0].path, filepath.Ext(files[0].path))
+		override := base + ".override" + filepath.Ext(files[0].path)
+		if _, err := os.Stat(override); err == nil {
+			newArgs = append(newArgs[:start+2], append([]string{"-f", override}, newArgs[start+2:]...)...)
+			files = append(files, fileArg{index: start + 3, path: override})
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "ezship-compose-")
+	if err != nil {
+		return args, nil, fmt.Errorf("failed to create compose work dir: %w", err)
+	}
+	rewrite := &composeRewrite{dir: dir, files: make(map[string]string)}
+
+	var projectDir string
+	for n, f := range files {
+		if f.path == "-" {
+			continue // stdin can't be translated ahead of time
+		}
+		original, err := filepath.Abs(f.path)
+		if err != nil {
+			rewrite.Cleanup()
+			return args, nil, err
+		}
+		data, err := os.ReadFile(original)
+		if err != nil {
+			continue // let compose report the missing file itself
+		}
+		if projectDir == "" {
+			projectDir = filepath.Dir(original)
+		}
+
+		// Keep the base name so messages still mention the file the user knows
+		copyDir := filepath.Join(dir, fmt.Sprint(n))
+		os.MkdirAll(copyDir, 0755)
+		copyPath := filepath.Join(copyDir, filepath.Base(original))
+		if err := os.WriteFile(copyPath, []byte(TranslateComposeFile(string(data))), 0644); err != nil {
+			rewrite.Cleanup()
+			return args, nil, fmt.Errorf("failed to write translated compose file: %w", err)
+		}
+
+		rewrite.files[TranslatePath(copyPath)] = original
+		newArgs[f.index] = f.prefix + copyPath
+	}
+
+	// Relative paths in the files must still resolve against the original project directory
+	if !hasProjectDir && projectDir != "" {
+		newArgs = append(newArgs[:start], append([]string{"--project-directory", projectDir}, newArgs[start:]...)...)
+	}
+
+	return newArgs, rewrite, nil
+}
+
+// RestorePaths replaces references to the translated copies with the original file paths
+func (c *composeRewrite) RestorePaths(s string) string {
+	for translated, original := range c.files {
+		s = strings.ReplaceAll(s, translated, original)
+	}
+	return s
+}
+
+// Cleanup removes the translated copies
+func (c *composeRewrite) Cleanup() {
+	if c != nil {
+		os.RemoveAll(c.dir)
+	}
+}
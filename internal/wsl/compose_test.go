@@ -0,0 +1,79 @@
+package wsl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTranslateComposeLine(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`      - C:\data:/data`, `      - /mnt/c/data:/data`},
+		{`      - "D:\\proj\\src:/app:ro"`, `      - "/mnt/d/proj/src:/app:ro"`},
+		{`    env_file: .\config\app.env`, `    env_file: ./config/app.env`},
+		{`      context: ..\backend`, `      context: ../backend`},
+		{`      source: C:\Users\me\certs`, `      source: /mnt/c/Users/me/certs`},
+		{`    image: nginx:1.25`, `    image: nginx:1.25`},
+		{`      - data:/var/lib/postgresql/data`, `      - data:/var/lib/postgresql/data`},
+		{`      - "8080:80"`, `      - "8080:80"`},
+	}
+
+	for _, tt := range tests {
+		if got := TranslateComposeLine(tt.input); got != tt.expected {
+			t.Errorf("TranslateComposeLine(%q) = %q; want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestPrepareCompose(t *testing.T) {
+	dir := t.TempDir()
+	original := filepath.Join(dir, "docker-compose.yml")
+	content := "services:\n  web:\n    volumes:\n      - C:\\site:/usr/share/nginx/html\n"
+	os.WriteFile(original, []byte(content), 0644)
+
+	args, rewrite, err := prepareCompose("docker", []string{"compose", "-f", original, "up", "-d"})
+	if err != nil {
+		t.Fatalf("prepareCompose failed: %v", err)
+	}
+	defer rewrite.Cleanup()
+
+	// compose --project-directory <dir> -f <copy> up -d
+	if len(args) != 7 || args[1] != "--project-directory" || args[2] != dir {
+		t.Fatalf("Unexpected args: %v", args)
+	}
+	copyPath := args[4]
+	if copyPath == original || filepath.Base(copyPath) != "docker-compose.yml" {
+		t.Errorf("Expected a translated copy with the same name, got %s", copyPath)
+	}
+
+	data, _ := os.ReadFile(copyPath)
+	translated := string(data)
+	if !strings.Contains(translated, "/mnt/c/site:/usr/share/nginx/html") {
+		t.Errorf("Expected bind mount to be translated, got:\n%s", translated)
+	}
+	if strings.Count(translated, "\n") != strings.Count(content, "\n") {
+		t.Error("Translation must keep line numbers intact")
+	}
+
+	msg := "yaml: line 4: mapping error in " + TranslatePath(copyPath)
+	if got := rewrite.RestorePaths(msg); got != "yaml: line 4: mapping error in "+original {
+		t.Errorf("RestorePaths = %s", got)
+	}
+
+	rewrite.Cleanup()
+	if _, err := os.Stat(copyPath); !os.IsNotExist(err) {
+		t.Error("Expected translated copy to be removed on cleanup")
+	}
+}
+
+func TestPrepareComposeIgnoresOtherCommands(t *testing.T) {
+	args := []string{"run", "-v", "C:\\x:/x", "alpine"}
+	out, rewrite, err := prepareCompose("docker", args)
+	if err != nil || rewrite != nil || len(out) != len(args) {
+		t.Errorf("Expected non-compose command to be untouched, got %v %v %v", out, rewrite, err)
+	}
+}
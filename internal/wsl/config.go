@@ -10,6 +10,55 @@ type Config struct {
 	DefaultEngine   string `json:"default_engine"`
 	AutoStartDaemon bool   `json:"auto_start_daemon"`
 	Theme           string `json:"theme"`
+	// EnvPassthrough lists extra Windows variables forwarded to proxied commands.
+	// Append "/p" to a name to translate its value as a path (e.g. "MY_DATA_DIR/p").
+	EnvPassthrough []string `json:"env_passthrough,omitempty"`
+	// ReadyCacheTTL is how many seconds the proxy trusts a previous engine check
+	// (0 uses the default, a negative value always runs the full check).
+	ReadyCacheTTL int `json:"ready_cache_ttl,omitempty"`
+	// ExtraAliases are additional tool names (helm, crictl, ...) the binary proxies when copied under that name
+	ExtraAliases []string `json:"extra_aliases,omitempty"`
+	// ToolEngines overrides which engine a proxied tool needs running ("" for none)
+	ToolEngines map[string]string `json:"tool_engines,omitempty"`
+	// ReversePaths rewrites /mnt/c/... in engine output back to C:\... when printing to a console
+	ReversePaths bool `json:"reverse_paths,omitempty"`
+	// AuditLog records every proxied command in a JSONL file (see 'ezship history')
+	AuditLog     bool   `json:"audit_log,omitempty"`
+	AuditLogPath string `json:"audit_log_path,omitempty"`
+	// ArtifactCache is a directory with a bundle from 'ezship bundle create' for offline installs
+	ArtifactCache string `json:"artifact_cache,omitempty"`
+	// BaseImage is the base distribution of the ezship distro (see 'ezship setup --base'),
+	// recorded at import so engines are installed with the matching package manager
+	BaseImage string `json:"base_image,omitempty"`
+	// BackupDir is where 'ezship backup' stores archives (default %APPDATA%\ezship\backups)
+	BackupDir string `json:"backup_dir,omitempty"`
+	// BackupRetention is how many backups per profile are kept (0 uses the default, negative keeps all)
+	BackupRetention int `json:"backup_retention,omitempty"`
+	// AutoSnapshot exports the distro before reset, vacuum, prune and engine upgrades (see 'ezship
+	// snapshot rollback'); SnapshotRetention is how many are kept per profile (0 uses the
+	// default, negative keeps all)
+	AutoSnapshot      bool `json:"auto_snapshot,omitempty"`
+	SnapshotRetention int  `json:"snapshot_retention,omitempty"`
+	// VacuumMethod is the default compaction for 'ezship vacuum': diskpart, optimize-vhd or sparse
+	// ("" uses Optimize-VHD when Hyper-V tools are installed, diskpart otherwise)
+	VacuumMethod string `json:"vacuum_method,omitempty"`
+	// PruneUntil only lets 'ezship prune' remove objects older than this duration (e.g. "72h");
+	// PruneVolumes also removes unused volumes, which are kept by default
+	PruneUntil   string `json:"prune_until,omitempty"`
+	PruneVolumes bool   `json:"prune_volumes,omitempty"`
+	// Maintenance schedules prune and vacuum jobs, run by 'ezship agent' or after a proxied
+	// command when one is overdue
+	Maintenance []MaintenanceJob `json:"maintenance,omitempty"`
+	// DistroUser is the non-root default user created in new distros, which proxied commands
+	// run as ("" uses the Windows account name, "root" keeps everything running as root)
+	DistroUser string `json:"distro_user,omitempty"`
+	// InstallDir is where the default profile's virtual disk lives (default %APPDATA%\ezship);
+	// set by 'ezship setup --install-dir' and 'ezship move'
+	InstallDir string `json:"install_dir,omitempty"`
+	// DefaultProfile is the profile used without --profile or EZSHIP_PROFILE ("" is "default")
+	DefaultProfile string `json:"default_profile,omitempty"`
+	// Profiles holds the sections of the named environments created with 'ezship env create'
+	Profiles map[string]ProfileConfig `json:"profiles,omitempty"`
 }
 
 func GetConfigPath() string {
@@ -17,6 +66,12 @@ func GetConfigPath() string {
 	return filepath.Join(appData, "ezship", "config.json")
 }
 
+// GetStateDir returns the directory holding runtime state such as readiness markers
+func GetStateDir() string {
+	appData := os.Getenv("APPDATA")
+	return filepath.Join(appData, "ezship", "state")
+}
+
 func LoadConfig() Config {
 	path := GetConfigPath()
 	data, err := os.ReadFile(path)
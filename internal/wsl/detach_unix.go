@@ -0,0 +1,10 @@
+//go:build !windows
+
+package wsl
+
+import "syscall"
+
+// detachedProcAttr starts a background process in its own session
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
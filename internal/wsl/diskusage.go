@@ -0,0 +1,246 @@
+package wsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Thresholds above which the report recommends a prune or a vacuum
+const (
+	pruneAdviceBytes  = 1 << 30
+	vacuumAdviceBytes = 2 << 30
+)
+
+// containerdDirs are the on-disk stores of engines without a 'system df' command
+var containerdDirs = []struct{ source, dir string }{
+	{"nerdctl", "/var/lib/containerd"},
+	{"k3s", "/var/lib/rancher/k3s/agent/containerd"},
+}
+
+// engineRunning reports whether an engine's daemon is up. Tests swap it out.
+var engineRunning = func(engine string) bool {
+	return GetEngineStatus(engine).Running
+}
+
+// DiskUsageItem is one row of the report (e.g. docker images)
+type DiskUsageItem struct {
+	Source      string `json:"source"`
+	Type        string `json:"type"`
+	Count       *int   `json:"count,omitempty"` // nil where the source reports none
+	Active      *int   `json:"active,omitempty"`
+	Size        int64  `json:"size"`
+	Reclaimable int64  `json:"reclaimable"`
+}
+
+// DiskUsageReport combines the engines' usage with the distro filesystem and the host-side disk
+type DiskUsageReport struct {
+	Items          []DiskUsageItem `json:"items"`
+	FilesystemSize int64           `json:"filesystem_size"`
+	FilesystemUsed int64           `json:"filesystem_used"`
+	VHDXPath       string          `json:"vhdx_path"`
+	VHDXSize       int64           `json:"vhdx_size"`
+	Errors         []string        `json:"errors,omitempty"`
+}
+
+// EngineReclaimable is what pruning the engines could free inside the distro
+func (r DiskUsageReport) EngineReclaimable() int64 {
+	var n int64
+	for _, it := range r.Items {
+		n += it.Reclaimable
+	}
+	return n
+}
+
+// VacuumReclaimable estimates what compacting the vhdx could return to the host
+func (r DiskUsageReport) VacuumReclaimable() int64 {
+	if r.VHDXSize > r.FilesystemUsed && r.FilesystemUsed > 0 {
+		return r.VHDXSize - r.FilesystemUsed
+	}
+	return 0
+}
+
+// Advice suggests prune and/or vacuum when they would free a meaningful amount of space
+func (r DiskUsageReport) Advice() []string {
+	var advice []string
+	if n := r.EngineReclaimable(); n >= pruneAdviceBytes {
+		advice = append(advice, fmt.Sprintf("Run 'ezship prune' to free about %s inside the distro.", FormatSize(n)))
+	}
+	if n := r.VacuumReclaimable(); n >= vacuumAdviceBytes {
+		advice = append(advice, fmt.Sprintf("Run 'ezship vacuum' to return about %s to Windows.", FormatSize(n)))
+	}
+	return advice
+}
+
+// parseHumanSize parses sizes printed by docker/podman ("1.5GB", "12.3kB", "500MB (40%)")
+func parseHumanSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "("); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	end := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if end < 0 {
+		end = len(s)
+	}
+	value, err := strconv.ParseFloat(s[:end], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	// docker and podman print decimal units
+	mult := map[string]float64{"": 1, "B": 1, "KB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12, "PB": 1e15}
+	m, ok := mult[strings.ToUpper(strings.TrimSpace(s[end:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(value * m), nil
+}
+
+// systemDfRow is a line of '<engine> system df --format {{json .}}' (docker and podman spellings)
+type systemDfRow struct {
+	Type        string
+	TotalCount  json.RawMessage
+	Total       json.RawMessage
+	Active      json.RawMessage
+	Size        string
+	Reclaimable string
+}
+
+// jsonCount reads a count that docker prints as a string and podman as a number (nil if missing)
+func jsonCount(raw json.RawMessage) *int {
+	n, err := strconv.Atoi(strings.Trim(string(raw), `"`))
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// parseSystemDf converts 'system df' JSON lines into report items
+func parseSystemDf(source, output string) ([]DiskUsageItem, error) {
+	var items []DiskUsageItem
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var row systemDfRow
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			return nil, fmt.Errorf("unexpected %s system df output: %w", source, err)
+		}
+		count := row.TotalCount
+		if count == nil {
+			count = row.Total
+		}
+		size, _ := parseHumanSize(row.Size)
+		reclaimable, _ := parseHumanSize(row.Reclaimable)
+		items = append(items, DiskUsageItem{
+			Source:      source,
+			Type:        row.Type,
+			Count:       jsonCount(count),
+			Active:      jsonCount(row.Active),
+			Size:        size,
+			Reclaimable: reclaimable,
+		})
+	}
+	return items, nil
+}
+
+// parseDu converts 'du -sk <dirs>' output into a map of directory to bytes
+func parseDu(output string) map[string]int64 {
+	sizes := make(map[string]int64)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if kb, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+			sizes[fields[1]] = kb * 1024
+		}
+	}
+	return sizes
+}
+
+// GetDiskUsage collects the disk usage report for the active distro
+func GetDiskUsage() DiskUsageReport {
+	var r DiskUsageReport
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	addErr := func(format string, args ...any) {
+		mu.Lock()
+		r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+		mu.Unlock()
+	}
+
+	// Engines with a 'system df' (only answers while the daemon runs)
+	for _, engine := range []string{"docker", "podman"} {
+		wg.Add(1)
+		go func(engine string) {
+			defer wg.Done()
+			if !engineRunning(engine) {
+				return
+			}
+			output, err := wslCommand(engineCommandArgs(engine, engine, "system", "df", "--format", "{{json .}}")...).Output()
+			if err != nil {
+				addErr("%s: %v", engine, err)
+				return
+			}
+			items, err := parseSystemDf(engine, string(output))
+			if err != nil {
+				addErr("%v", err)
+				return
+			}
+			mu.Lock()
+			r.Items = append(r.Items, items...)
+			mu.Unlock()
+		}(engine)
+	}
+
+	// containerd stores (nerdctl, k3s) are measured directly
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		args := []string{"-d", DistroName, "-u", "root", "-e", "du", "-sk"}
+		for _, c := range containerdDirs {
+			args = append(args, c.dir)
+		}
+		// du fails for the directories that do not exist, but still prints the others
+		output, _ := wslCommand(args...).Output()
+		sizes := parseDu(string(output))
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range containerdDirs {
+			if size, ok := sizes[c.dir]; ok {
+				r.Items = append(r.Items, DiskUsageItem{Source: c.source, Type: "containerd store", Size: size})
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		size, used, err := distroFilesystem()
+		if err != nil {
+			addErr("%v", err)
+			return
+		}
+		mu.Lock()
+		r.FilesystemSize, r.FilesystemUsed = size, used
+		mu.Unlock()
+	}()
+
+	wg.Wait()
+
+	r.VHDXPath = filepath.Join(GetInstallDir(), "ext4.vhdx")
+	if stat, err := os.Stat(r.VHDXPath); err == nil {
+		r.VHDXSize = stat.Size()
+	}
+
+	// Stable order regardless of which goroutine finished first
+	order := map[string]int{"docker": 0, "podman": 1, "nerdctl": 2, "k3s": 3}
+	sort.SliceStable(r.Items, func(i, j int) bool { return order[r.Items[i].Source] < order[r.Items[j].Source] })
+	return r
+}
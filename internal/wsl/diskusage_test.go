@@ -0,0 +1,129 @@
+package wsl
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const dockerSystemDf = `{"Active":"2","Reclaimable":"1.2GB (60%)","Size":"2GB","TotalCount":"5","Type":"Images"}
+{"Active":"1","Reclaimable":"0B (0%)","Size":"12.5kB","TotalCount":"1","Type":"Containers"}
+{"Active":"0","Reclaimable":"300MB","Size":"300MB","TotalCount":"2","Type":"Local Volumes"}
+{"Active":"0","Reclaimable":"512MB","Size":"512MB","TotalCount":"10","Type":"Build Cache"}
+`
+
+func TestParseHumanSize(t *testing.T) {
+	tests := map[string]int64{
+		"0B":          0,
+		"12.5kB":      12500,
+		"1.2GB (60%)": 1200000000,
+		"300 MB":      300000000,
+		"42":          42,
+	}
+	for in, want := range tests {
+		if got, err := parseHumanSize(in); err != nil || got != want {
+			t.Errorf("parseHumanSize(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "GB", "1.5XB"} {
+		if _, err := parseHumanSize(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+// countOf reads a reported count, -1 if it was not reported
+func countOf(n *int) int {
+	if n == nil {
+		return -1
+	}
+	return *n
+}
+
+func TestParseSystemDf(t *testing.T) {
+	items, err := parseSystemDf("docker", dockerSystemDf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 4 {
+		t.Fatalf("Expected 4 items, got %d", len(items))
+	}
+	if items[0].Type != "Images" || countOf(items[0].Count) != 5 || countOf(items[0].Active) != 2 || items[0].Reclaimable != 1200000000 {
+		t.Errorf("Unexpected images row: %+v", items[0])
+	}
+	if countOf(items[2].Active) != 0 {
+		t.Errorf("Expected no active volumes (0, not unreported), got %+v", items[2])
+	}
+	if items[3].Type != "Build Cache" || items[3].Size != 512000000 {
+		t.Errorf("Unexpected build cache row: %+v", items[3])
+	}
+
+	// podman prints numeric counts under Total
+	items, err = parseSystemDf("podman", `{"Type":"Images","Total":3,"Active":1,"Size":"1GB","Reclaimable":"500MB"}`)
+	if err != nil || len(items) != 1 || countOf(items[0].Count) != 3 || countOf(items[0].Active) != 1 {
+		t.Errorf("Unexpected podman parse: %+v, %v", items, err)
+	}
+
+	items, _ = parseSystemDf("podman", `{"Type":"Images","Size":"1GB","Reclaimable":"0B"}`)
+	if len(items) != 1 || items[0].Count != nil || items[0].Active != nil {
+		t.Errorf("Expected missing counts to stay unreported: %+v", items)
+	}
+
+	if _, err := parseSystemDf("docker", "not json"); err == nil {
+		t.Error("Expected error for non-JSON output")
+	}
+}
+
+func TestDiskUsageAdvice(t *testing.T) {
+	r := DiskUsageReport{FilesystemUsed: 4 << 30, VHDXSize: 5 << 30}
+	if advice := r.Advice(); len(advice) != 0 {
+		t.Errorf("Expected no advice, got %v", advice)
+	}
+
+	r.Items = []DiskUsageItem{{Source: "docker", Type: "Images", Reclaimable: 2 << 30}}
+	r.VHDXSize = 10 << 30
+	advice := r.Advice()
+	if len(advice) != 2 || !strings.Contains(advice[0], "ezship prune") || !strings.Contains(advice[1], "ezship vacuum") {
+		t.Errorf("Expected prune and vacuum advice, got %v", advice)
+	}
+}
+
+func TestGetDiskUsage(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	os.MkdirAll(GetInstallDir(), 0755)
+	os.WriteFile(filepath.Join(GetInstallDir(), "ext4.vhdx"), make([]byte, 4096), 0644)
+
+	origWSL, origRunning := wslCommand, engineRunning
+	t.Cleanup(func() { wslCommand, engineRunning = origWSL, origRunning })
+	engineRunning = func(engine string) bool { return engine == "docker" }
+	wslCommand = func(args ...string) *exec.Cmd {
+		cmd := strings.Join(args, " ")
+		switch {
+		case strings.Contains(cmd, "docker system df"):
+			return stubCommand(dockerSystemDf)
+		case strings.Contains(cmd, "podman"):
+			t.Error("podman is not running and should not be queried")
+		case strings.Contains(cmd, "du -sk"):
+			return stubCommand("2048\t/var/lib/rancher/k3s/agent/containerd\n")
+		case strings.Contains(cmd, "df -k /"):
+			return stubCommand(gnuDf)
+		}
+		return stubCommand("")
+	}
+
+	r := GetDiskUsage()
+	if len(r.Errors) != 0 {
+		t.Fatalf("Unexpected errors: %v", r.Errors)
+	}
+	if len(r.Items) != 5 {
+		t.Fatalf("Expected 4 docker rows and the k3s store, got %+v", r.Items)
+	}
+	if last := r.Items[4]; last.Source != "k3s" || last.Size != 2048*1024 {
+		t.Errorf("Unexpected k3s row: %+v", last)
+	}
+	if r.FilesystemUsed != 4194304*1024 || r.VHDXSize != 4096 {
+		t.Errorf("Unexpected filesystem %d or vhdx %d", r.FilesystemUsed, r.VHDXSize)
+	}
+}
@@ -0,0 +1,295 @@
+package wsl
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	// downloadRetries is how many times a failed download is attempted again
+	downloadRetries = 5
+	// downloadBackoff is the wait before the first retry; it doubles on each attempt
+	downloadBackoff = 2 * time.Second
+	// progressInterval throttles DownloadProgress callbacks
+	progressInterval = 200 * time.Millisecond
+	// downloadStallTimeout aborts a request that receives nothing for this long, so it is retried
+	downloadStallTimeout = 60 * time.Second
+)
+
+// downloadClient bounds connecting and waiting for a response; downloadPart also catches
+// transfers that stall halfway
+var downloadClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout:   15 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+	},
+}
+
+// DownloadProgress, when set, receives the progress of large downloads (total is -1 if unknown).
+// The CLI draws a progress bar with it and the TUI forwards it to the dashboard.
+var DownloadProgress func(name string, done, total int64)
+
+// errNotResumable signals that the server ignored our Range request
+var errNotResumable = errors.New("server does not support resume")
+
+// httpStatusError is a download answered with an error status
+type httpStatusError struct {
+	code   int
+	status string
+}
+
+func (e *httpStatusError) Error() string {
+	return "server returned " + e.status
+}
+
+// retryable reports whether a failed download may succeed when attempted again. Client
+// errors (404, 403, ...) will not, except timeouts and rate limiting.
+func retryable(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && statusErr.code >= 400 && statusErr.code < 500 {
+		return statusErr.code == http.StatusRequestTimeout || statusErr.code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// validatorPath stores the ETag (or Last-Modified) of the file being downloaded into partPath,
+// so a resume only appends to a part of the same file ("release" and "latest" URLs change)
+func validatorPath(partPath string) string {
+	return partPath + ".validator"
+}
+
+// FetchChecksum looks up the SHA256 of name in a SHA256SUMS file
+// ("<hash> *<name>" or "<hash>  <name>" lines, as published by Ubuntu and most mirrors)
+func FetchChecksum(sumsURL, name string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), downloadStallTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sumsURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksums: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch checksums: server returned %s", resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no checksum for %s in %s", name, sumsURL)
+}
+
+// checksumURL returns the SHA256SUMS location next to a download URL
+func checksumURL(url string) string {
+	return url[:strings.LastIndex(url, "/")+1] + "SHA256SUMS"
+}
+
+// downloadFile downloads url to dest, resuming a previous partial download, retrying with
+// backoff, and verifying the SHA256 (if given) before atomically moving it into place.
+// An interrupted download therefore never leaves a truncated file at dest.
+func downloadFile(url, dest, sha string) error {
+	partPath := dest + ".part"
+	name := path.Base(url)
+
+	// Without a validator or a checksum nothing tells whether a part left by an earlier run
+	// belongs to the file the URL serves now
+	if _, err := os.Stat(validatorPath(partPath)); err != nil && sha == "" {
+		os.Remove(partPath)
+	}
+
+	var lastErr error
+	backoff := downloadBackoff
+	for attempt := 0; attempt <= downloadRetries; attempt++ {
+		if attempt > 0 {
+			fmt.Fprintf(os.Stderr, "Download failed (%v), retrying in %s...\n", lastErr, backoff)
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		lastErr = downloadPart(url, partPath, name)
+		if errors.Is(lastErr, errNotResumable) {
+			// Start from scratch without counting it as a failure
+			os.Remove(partPath)
+			os.Remove(validatorPath(partPath))
+			lastErr = downloadPart(url, partPath, name)
+		}
+		if lastErr == nil || !retryable(lastErr) {
+			break
+		}
+	}
+	if lastErr != nil {
+		return lastErr
+	}
+	os.Remove(validatorPath(partPath))
+
+	if sha != "" {
+		got, err := fileHash(partPath)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(got, sha) {
+			os.Remove(partPath)
+			os.Remove(validatorPath(partPath))
+			return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, sha, got)
+		}
+	}
+
+	return os.Rename(partPath, dest)
+}
+
+// downloadPart fetches the remainder of url into partPath, appending to what is already there
+func downloadPart(url, partPath, name string) error {
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	// The request is cancelled when no data arrives for downloadStallTimeout
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stall := time.AfterFunc(downloadStallTimeout, cancel)
+	defer stall.Stop()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		// If the file changed since the part was written, the server sends all of it (200)
+		if validator, err := os.ReadFile(validatorPath(partPath)); err == nil {
+			req.Header.Set("If-Range", string(validator))
+		}
+	}
+
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return stallError(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// Complete only if the part is exactly as long as the file ("bytes */<size>")
+		if resp.Header.Get("Content-Range") == fmt.Sprintf("bytes */%d", offset) {
+			return nil
+		}
+		return errNotResumable
+	case offset > 0 && resp.StatusCode == http.StatusOK:
+		return errNotResumable
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent:
+		return &httpStatusError{code: resp.StatusCode, status: resp.Status}
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		validator := resp.Header.Get("ETag")
+		if validator == "" || strings.HasPrefix(validator, "W/") {
+			// Weak ETags cannot be used with If-Range
+			validator = resp.Header.Get("Last-Modified")
+		}
+		os.Remove(validatorPath(partPath))
+		if validator != "" {
+			if err := os.WriteFile(validatorPath(partPath), []byte(validator), 0644); err != nil {
+				return err
+			}
+		}
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if resp.StatusCode == http.StatusOK {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		offset = 0
+	}
+	out, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	pw := &progressWriter{name: name, done: offset, total: total}
+	pw.report(true)
+
+	body := &stallReader{r: resp.Body, timer: stall, timeout: downloadStallTimeout}
+	_, err = io.Copy(out, io.TeeReader(body, pw))
+	pw.report(true)
+	return stallError(ctx, err)
+}
+
+// stallReader pushes back its timer whenever data arrives
+type stallReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (s *stallReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if n > 0 {
+		s.timer.Reset(s.timeout)
+	}
+	return n, err
+}
+
+// stallError replaces the error of a request cancelled by the stall timer with a clearer one
+func stallError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("download stalled: no data for %s", downloadStallTimeout)
+	}
+	return err
+}
+
+// progressWriter counts bytes and forwards them to DownloadProgress at a limited rate
+type progressWriter struct {
+	name        string
+	done, total int64
+	last        time.Time
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.done += int64(len(b))
+	p.report(false)
+	return len(b), nil
+}
+
+func (p *progressWriter) report(force bool) {
+	if DownloadProgress == nil {
+		return
+	}
+	if !force && time.Since(p.last) < progressInterval {
+		return
+	}
+	p.last = time.Now()
+	DownloadProgress(p.name, p.done, p.total)
+}
+
+// verifyFile reports whether the file at path matches the expected SHA256
+func verifyFile(path, sha string) bool {
+	got, err := fileHash(path)
+	return err == nil && strings.EqualFold(got, sha)
+}
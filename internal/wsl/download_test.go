@@ -0,0 +1,225 @@
+package wsl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// rootfsServer serves a fake rootfs with Range support plus a SHA256SUMS file
+func rootfsServer(t *testing.T, data []byte, failFirst int32) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/release/rootfs.tar.xz", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= failFirst {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		// The ETag lets resumes check that the part belongs to this file (If-Range)
+		w.Header().Set("ETag", `"`+sha256Hex(data)[:16]+`"`)
+		http.ServeContent(w, r, "rootfs.tar.xz", time.Time{}, bytes.NewReader(data))
+	})
+	mux.HandleFunc("/release/SHA256SUMS", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s *other.img\n%s *rootfs.tar.xz\n", strings.Repeat("0", 64), sha256Hex(data))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func fastRetries(t *testing.T) {
+	orig := downloadBackoff
+	downloadBackoff = time.Millisecond
+	t.Cleanup(func() { downloadBackoff = orig })
+}
+
+func testPayload() []byte {
+	data := make([]byte, 1<<20)
+	rand.New(rand.NewSource(7)).Read(data)
+	return data
+}
+
+func TestFetchChecksum(t *testing.T) {
+	data := testPayload()
+	srv, _ := rootfsServer(t, data, 0)
+
+	url := srv.URL + "/release/rootfs.tar.xz"
+	sha, err := FetchChecksum(checksumURL(url), "rootfs.tar.xz")
+	if err != nil {
+		t.Fatalf("FetchChecksum failed: %v", err)
+	}
+	if sha != sha256Hex(data) {
+		t.Errorf("FetchChecksum = %s; want %s", sha, sha256Hex(data))
+	}
+
+	if _, err := FetchChecksum(checksumURL(url), "missing.tar.xz"); err == nil {
+		t.Error("Expected an error for a file without checksum")
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	data := testPayload()
+	srv, _ := rootfsServer(t, data, 0)
+	dest := filepath.Join(t.TempDir(), "rootfs.tar.xz")
+
+	// Simulate an interrupted earlier download
+	os.WriteFile(dest+".part", data[:len(data)/3], 0644)
+
+	var lastDone, lastTotal int64
+	DownloadProgress = func(name string, done, total int64) { lastDone, lastTotal = done, total }
+	defer func() { DownloadProgress = nil }()
+
+	if err := downloadFile(srv.URL+"/release/rootfs.tar.xz", dest, sha256Hex(data)); err != nil {
+		t.Fatalf("downloadFile failed: %v", err)
+	}
+
+	got, _ := os.ReadFile(dest)
+	if !bytes.Equal(got, data) {
+		t.Error("Resumed download does not match the source")
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Error("Expected part file to be renamed into place")
+	}
+	if lastDone != int64(len(data)) || lastTotal != int64(len(data)) {
+		t.Errorf("Expected final progress %d/%d, got %d/%d", len(data), len(data), lastDone, lastTotal)
+	}
+}
+
+func TestDownloadRetries(t *testing.T) {
+	fastRetries(t)
+	data := testPayload()
+	srv, requests := rootfsServer(t, data, 2)
+	dest := filepath.Join(t.TempDir(), "rootfs.tar.xz")
+
+	if err := downloadFile(srv.URL+"/release/rootfs.tar.xz", dest, sha256Hex(data)); err != nil {
+		t.Fatalf("downloadFile failed: %v", err)
+	}
+	if atomic.LoadInt32(requests) != 3 {
+		t.Errorf("Expected 3 requests (2 failures + success), got %d", *requests)
+	}
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	data := testPayload()
+	srv, _ := rootfsServer(t, data, 0)
+	dest := filepath.Join(t.TempDir(), "rootfs.tar.xz")
+
+	err := downloadFile(srv.URL+"/release/rootfs.tar.xz", dest, strings.Repeat("a", 64))
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Expected checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("A corrupt download must never reach the final path")
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Error("Expected corrupt part file to be discarded")
+	}
+}
+
+func TestDownloadResumeChangedFile(t *testing.T) {
+	data := testPayload()
+	srv, _ := rootfsServer(t, data, 0)
+	dest := filepath.Join(t.TempDir(), "rootfs.tar.xz")
+
+	// A part of an older release, which the "release" URL no longer serves
+	os.WriteFile(dest+".part", bytes.Repeat([]byte("old"), 1000), 0644)
+	os.WriteFile(validatorPath(dest+".part"), []byte(`"older-release"`), 0644)
+
+	if err := downloadFile(srv.URL+"/release/rootfs.tar.xz", dest, ""); err != nil {
+		t.Fatalf("downloadFile failed: %v", err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, data) {
+		t.Error("Expected the changed file to be downloaded from scratch")
+	}
+	if _, err := os.Stat(validatorPath(dest + ".part")); !os.IsNotExist(err) {
+		t.Error("Expected the validator to be removed with the part")
+	}
+}
+
+func TestDownloadDropsUnverifiablePart(t *testing.T) {
+	data := testPayload()
+	srv, _ := rootfsServer(t, data, 0)
+	dest := filepath.Join(t.TempDir(), "rootfs.tar.xz")
+
+	// Neither a validator nor a checksum: the part cannot be trusted
+	os.WriteFile(dest+".part", []byte("stale"), 0644)
+	if err := downloadFile(srv.URL+"/release/rootfs.tar.xz", dest, ""); err != nil {
+		t.Fatalf("downloadFile failed: %v", err)
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, data) {
+		t.Error("Expected the stale part to be discarded")
+	}
+}
+
+func TestDownloadClientErrorNotRetried(t *testing.T) {
+	fastRetries(t)
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	err := downloadFile(srv.URL+"/missing.tar.xz", filepath.Join(t.TempDir(), "x"), "")
+	if err == nil || atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("Expected a single failed request, got %d, %v", requests, err)
+	}
+}
+
+func TestDownloadStallRetried(t *testing.T) {
+	fastRetries(t)
+	orig := downloadStallTimeout
+	downloadStallTimeout = 200 * time.Millisecond
+	t.Cleanup(func() { downloadStallTimeout = orig })
+
+	data := testPayload()
+	etag := `"` + sha256Hex(data)[:16] + `"`
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if atomic.AddInt32(&requests, 1) == 1 {
+			// Send half of the file, then hang without closing the connection
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+			w.Write(data[:len(data)/2])
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+			case <-time.After(10 * time.Second):
+			}
+			return
+		}
+		http.ServeContent(w, r, "rootfs.tar.xz", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(srv.Close)
+	dest := filepath.Join(t.TempDir(), "rootfs.tar.xz")
+
+	start := time.Now()
+	if err := downloadFile(srv.URL+"/rootfs.tar.xz", dest, sha256Hex(data)); err != nil {
+		t.Fatalf("downloadFile failed: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("Expected the stalled transfer to be abandoned quickly")
+	}
+	if got, _ := os.ReadFile(dest); !bytes.Equal(got, data) {
+		t.Error("Expected the download to resume after the stall")
+	}
+	if atomic.LoadInt32(&requests) != 2 {
+		t.Errorf("Expected the stalled request and one resume, got %d", requests)
+	}
+}
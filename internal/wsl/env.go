@@ -0,0 +1,101 @@
+package wsl
+
+import (
+	"strings"
+)
+
+// defaultEnvPassthrough lists the variables forwarded to each engine out of the box.
+// Entries ending in "/p" hold paths (or ';'-separated path lists) and are translated.
+var defaultEnvPassthrough = map[string][]string{
+	"docker":         {"DOCKER_BUILDKIT", "BUILDKIT_PROGRESS", "DOCKER_DEFAULT_PLATFORM", "DOCKER_CONFIG/p", "COMPOSE_PROJECT_NAME", "COMPOSE_PROFILES", "COMPOSE_FILE/p"},
+	"podman":         {"BUILDAH_FORMAT", "COMPOSE_PROJECT_NAME", "CONTAINERS_CONF/p", "REGISTRY_AUTH_FILE/p"},
+	"nerdctl":        {"CONTAINERD_NAMESPACE", "CONTAINERD_SNAPSHOTTER", "NERDCTL_TOML/p"},
+	"kubectl":        {"KUBECONFIG/p"},
+	"k3d":            {"DOCKER_BUILDKIT", "KUBECONFIG/p"},
+	"docker-compose": {"DOCKER_BUILDKIT", "COMPOSE_PROJECT_NAME", "COMPOSE_PROFILES", "COMPOSE_FILE/p"},
+	"podman-compose": {"COMPOSE_PROJECT_NAME", "COMPOSE_PROFILES", "COMPOSE_FILE/p"},
+	"helm":           {"KUBECONFIG/p", "HELM_NAMESPACE", "HELM_KUBECONTEXT"},
+	"crictl":         {"CONTAINER_RUNTIME_ENDPOINT", "IMAGE_SERVICE_ENDPOINT"},
+	"ctr":            {"CONTAINERD_NAMESPACE"},
+}
+
+// parseEnvEntry splits an allowlist entry such as "KUBECONFIG/p" into its name and path flag
+func parseEnvEntry(entry string) (string, bool) {
+	name, flags, _ := strings.Cut(strings.TrimSpace(entry), "/")
+	return name, strings.Contains(flags, "p")
+}
+
+// translateEnvPath converts a Windows path list (';'-separated) into a Linux one (':'-separated)
+func translateEnvPath(value string) string {
+	parts := strings.Split(value, ";")
+	var out []string
+	for _, p := range parts {
+		if p == "" {
+			continue
+		}
+		out = append(out, TranslatePath(p))
+	}
+	return strings.Join(out, ":")
+}
+
+// ProxyEnv builds the environment for a proxied engine command. Allowed variables that are
+// set on the Windows side are copied (translated when they hold paths) and listed in WSLENV
+// so that wsl.exe exposes them inside the distro.
+func ProxyEnv(engine string, cfg Config, environ []string) []string {
+	entries := append([]string{}, defaultEnvPassthrough[engine]...)
+	entries = append(entries, cfg.EnvPassthrough...)
+
+	values := make(map[string]string)
+	var env []string
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		values[strings.ToUpper(name)] = value
+	}
+
+	forwarded := make(map[string]bool)
+	var names []string
+	for _, entry := range entries {
+		name, isPath := parseEnvEntry(entry)
+		if name == "" || forwarded[strings.ToUpper(name)] {
+			continue
+		}
+		value, ok := values[strings.ToUpper(name)]
+		if !ok {
+			continue
+		}
+		if isPath {
+			value = translateEnvPath(value)
+		}
+		forwarded[strings.ToUpper(name)] = true
+		names = append(names, name)
+		env = append(env, name+"="+value)
+	}
+
+	if len(names) == 0 {
+		return environ
+	}
+
+	// Keep the user's own WSLENV entries, except the ones we already translated
+	var wslenv []string
+	for _, e := range strings.Split(values["WSLENV"], ":") {
+		name, _ := parseEnvEntry(e)
+		if name == "" || forwarded[strings.ToUpper(name)] {
+			continue
+		}
+		wslenv = append(wslenv, e)
+	}
+	wslenv = append(wslenv, names...)
+
+	var result []string
+	for _, kv := range environ {
+		name, _, _ := strings.Cut(kv, "=")
+		upper := strings.ToUpper(name)
+		if forwarded[upper] || upper == "WSLENV" {
+			continue
+		}
+		result = append(result, kv)
+	}
+	result = append(result, env...)
+	result = append(result, "WSLENV="+strings.Join(wslenv, ":"))
+	return result
+}
@@ -0,0 +1,71 @@
+package wsl
+
+import (
+	"strings"
+	"testing"
+)
+
+func envMap(env []string) map[string]string {
+	m := make(map[string]string)
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		m[name] = value
+	}
+	return m
+}
+
+func TestProxyEnvDefaults(t *testing.T) {
+	environ := []string{
+		"PATH=C:\\Windows",
+		"DOCKER_BUILDKIT=1",
+		"DOCKER_CONFIG=C:\\Users\\me\\.docker",
+	}
+
+	env := envMap(ProxyEnv("docker", Config{}, environ))
+	if env["DOCKER_BUILDKIT"] != "1" {
+		t.Errorf("DOCKER_BUILDKIT = %s; want 1", env["DOCKER_BUILDKIT"])
+	}
+	if env["DOCKER_CONFIG"] != "/mnt/c/Users/me/.docker" {
+		t.Errorf("DOCKER_CONFIG = %s; want /mnt/c/Users/me/.docker", env["DOCKER_CONFIG"])
+	}
+	if env["WSLENV"] != "DOCKER_BUILDKIT:DOCKER_CONFIG" {
+		t.Errorf("WSLENV = %s; want DOCKER_BUILDKIT:DOCKER_CONFIG", env["WSLENV"])
+	}
+	if env["PATH"] != "C:\\Windows" {
+		t.Errorf("PATH should be left untouched, got %s", env["PATH"])
+	}
+}
+
+func TestProxyEnvPathList(t *testing.T) {
+	environ := []string{"KUBECONFIG=C:\\a\\config;D:\\b\\config"}
+
+	env := envMap(ProxyEnv("kubectl", Config{}, environ))
+	if env["KUBECONFIG"] != "/mnt/c/a/config:/mnt/d/b/config" {
+		t.Errorf("KUBECONFIG = %s; want /mnt/c/a/config:/mnt/d/b/config", env["KUBECONFIG"])
+	}
+}
+
+func TestProxyEnvConfigAndExistingWSLENV(t *testing.T) {
+	cfg := Config{EnvPassthrough: []string{"APP_VERSION", "DATA_DIR/p"}}
+	environ := []string{
+		"WSLENV=USERPROFILE/p:DATA_DIR/p",
+		"APP_VERSION=1.2.3",
+		"DATA_DIR=D:\\data",
+	}
+
+	env := envMap(ProxyEnv("podman", cfg, environ))
+	if env["DATA_DIR"] != "/mnt/d/data" {
+		t.Errorf("DATA_DIR = %s; want /mnt/d/data", env["DATA_DIR"])
+	}
+	if env["WSLENV"] != "USERPROFILE/p:APP_VERSION:DATA_DIR" {
+		t.Errorf("WSLENV = %s; want USERPROFILE/p:APP_VERSION:DATA_DIR", env["WSLENV"])
+	}
+}
+
+func TestProxyEnvNothingToForward(t *testing.T) {
+	environ := []string{"PATH=C:\\Windows"}
+	env := ProxyEnv("docker", Config{}, environ)
+	if len(env) != 1 {
+		t.Errorf("Expected environment to be unchanged, got %v", env)
+	}
+}
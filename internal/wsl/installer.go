@@ -2,72 +2,213 @@ package wsl
 
 import (
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 const (
-	UbuntuURL = "https://cloud-images.ubuntu.com/minimal/releases/noble/release/ubuntu-24.04-minimal-cloudimg-amd64-root.tar.xz"
+	UbuntuURL     = "https://cloud-images.ubuntu.com/minimal/releases/noble/release/ubuntu-24.04-minimal-cloudimg-amd64-root.tar.xz"
+	Ubuntu2204URL = "https://cloud-images.ubuntu.com/minimal/releases/jammy/release/ubuntu-22.04-minimal-cloudimg-amd64-root.tar.xz"
+	Debian12URL   = "https://github.com/debuerreotype/docker-debian-artifacts/raw/dist-amd64/bookworm/rootfs.tar.xz"
+	AlpineURL     = "https://dl-cdn.alpinelinux.org/alpine/v3.20/releases/x86_64/alpine-minirootfs-3.20.3-x86_64.tar.gz"
 )
 
-// SetupDistro downloads the Ubuntu rootfs and imports it into WSL
+// SetupOptions customizes how the ezship distro is created
+type SetupOptions struct {
+	// Rootfs is a local tarball or URL to import instead of the base image download
+	Rootfs string
+	// Base is the base image name (see BaseImages); "" keeps the one in the config.
+	// With Rootfs it tells ezship which package manager the custom image uses.
+	Base string
+	// InstallDir places the distro's virtual disk somewhere other than %APPDATA% (e.g. another drive)
+	InstallDir string
+}
+
+// SetupDistro downloads the configured base image and imports it into WSL
 func SetupDistro() error {
-	appData := os.Getenv("APPDATA")
-	installDir := filepath.Join(appData, "ezship")
-	rootfsPath := filepath.Join(installDir, "ubuntu-rootfs.tar.xz")
+	return SetupDistroWithOptions(SetupOptions{})
+}
 
-	// Create install directory
-	if err := os.MkdirAll(installDir, 0755); err != nil {
-		return fmt.Errorf("failed to create install directory: %w", err)
-	}
+// SetupDistroWithOptions imports the distro from a custom rootfs or the artifact cache if given
+func SetupDistroWithOptions(opts SetupOptions) error {
+	return withLock(OperationLock, "setting up distro", func() error {
+		return setupDistro(opts)
+	})
+}
 
-	// Download Ubuntu rootfs if not exists
-	if _, err := os.Stat(rootfsPath); os.IsNotExist(err) {
-		if err := downloadFile(UbuntuURL, rootfsPath); err != nil {
-			return fmt.Errorf("failed to download Ubuntu: %w", err)
+func setupDistro(opts SetupOptions) error {
+	appData := os.Getenv("APPDATA")
+	// Downloaded images are shared by all profiles
+	downloadDir := filepath.Join(appData, "ezship")
+
+	cfg := LoadConfig()
+	installDir := ProfileInstallDir(activeProfile, cfg)
+	if opts.InstallDir != "" {
+		dir, err := filepath.Abs(opts.InstallDir)
+		if err != nil {
+			return fmt.Errorf("invalid install directory: %w", err)
+		}
+		installDir = dir
+	}
+	base := CurrentBaseImage(cfg)
+	if opts.Base != "" {
+		b, err := GetBaseImage(opts.Base)
+		if err != nil {
+			return err
 		}
+		base = b
 	}
+	rootfsPath := filepath.Join(downloadDir, base.rootfsFile())
 
-	// Check if already installed
+	// Create download directory (the install directory is created by the import)
+	if err := os.MkdirAll(downloadDir, 0755); err != nil {
+		return fmt.Errorf("failed to create install directory: %w", err)
+	}
+
+	// Check if already installed (the rootfs is only needed for the import)
 	installed, err := IsDistroInstalled()
 	if err == nil && installed {
+		if opts.InstallDir != "" && filepath.Clean(installDir) != filepath.Clean(ProfileInstallDir(activeProfile, cfg)) {
+			fmt.Printf("ezship distro already lives in %s; use 'ezship move %s' to relocate it.\n", ProfileInstallDir(activeProfile, cfg), opts.InstallDir)
+			return nil
+		}
+		if opts.Base != "" && base.Name != CurrentBaseImage(cfg).Name {
+			fmt.Printf("ezship distro already imported from %s; reset it to switch to %s.\n", CurrentBaseImage(cfg).Name, base.Name)
+			return nil
+		}
 		fmt.Println("ezship distro already imported. Skipping.")
 		return nil
 	}
 
-	// Import distro
-	cmd := exec.Command("wsl", "--import", DistroName, installDir, rootfsPath, "--version", "2")
+	// Offline sources: an explicit --rootfs, or the rootfs shipped in the artifact cache
+	if opts.Rootfs == "" {
+		cache := GetCacheDir(cfg)
+		if m, err := LoadBundleManifest(cache); err == nil && m.Rootfs != "" && m.BaseImage().Name == base.Name {
+			if err := m.Verify(cache, m.Rootfs); err != nil {
+				return err
+			}
+			fmt.Printf("Using %s image from the artifact cache.\n", base.Title)
+			return createDistro(installDir, filepath.Join(cache, filepath.FromSlash(m.Rootfs)), base)
+		}
+	} else if strings.HasPrefix(opts.Rootfs, "http://") || strings.HasPrefix(opts.Rootfs, "https://") {
+		rootfsPath = filepath.Join(downloadDir, path.Base(opts.Rootfs))
+		if _, err := os.Stat(rootfsPath); os.IsNotExist(err) {
+			sha, _ := FetchChecksum(checksumURL(opts.Rootfs), path.Base(opts.Rootfs))
+			if err := downloadFile(opts.Rootfs, rootfsPath, sha); err != nil {
+				return fmt.Errorf("failed to download rootfs: %w", err)
+			}
+		}
+		return createDistro(installDir, rootfsPath, base)
+	} else {
+		if _, err := os.Stat(opts.Rootfs); err != nil {
+			return fmt.Errorf("rootfs not found: %w", err)
+		}
+		return createDistro(installDir, opts.Rootfs, base)
+	}
+
+	if err := base.checkVerifiable(); err != nil {
+		return err
+	}
+
+	// Verify an existing rootfs (an older ezship may have left a truncated one behind)
+	sha, sumErr := FetchChecksum(base.SumsURL, path.Base(base.URL))
+	if sumErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not verify the %s image: %v\n", base.Title, sumErr)
+	}
+	if _, err := os.Stat(rootfsPath); err == nil && sha != "" && !verifyFile(rootfsPath, sha) {
+		fmt.Fprintf(os.Stderr, "Existing %s image is incomplete or corrupt, downloading it again...\n", base.Title)
+		os.Remove(rootfsPath)
+	}
+
+	// Download the rootfs if not exists
+	if _, err := os.Stat(rootfsPath); os.IsNotExist(err) {
+		fmt.Printf("Downloading %s...\n", base.Title)
+		if err := downloadFile(base.URL, rootfsPath, sha); err != nil {
+			return fmt.Errorf("failed to download %s: %w", base.Title, err)
+		}
+	}
+
+	if err := importDistro(installDir, rootfsPath, base); err != nil {
+		if sha == "" {
+			// Unverified image: drop it so the next setup downloads a fresh copy
+			os.Remove(rootfsPath)
+		}
+		return err
+	}
+	return provisionUser(base)
+}
+
+// createDistro imports a fresh distro from a base rootfs and creates its default user
+func createDistro(installDir, rootfsPath string, base BaseImage) error {
+	if err := importDistro(installDir, rootfsPath, base); err != nil {
+		return err
+	}
+	return provisionUser(base)
+}
+
+// importDistro registers the ezship distro from a rootfs tarball and records its base image and location
+func importDistro(installDir, rootfsPath string, base BaseImage) error {
+	if err := os.MkdirAll(installDir, 0755); err != nil {
+		return fmt.Errorf("failed to create install directory: %w", err)
+	}
+	cmd := wslCommand("--import", DistroName, installDir, rootfsPath, "--version", "2")
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("failed to import distro: %s (%w)", string(output), err)
 	}
 
+	// InstallEngine picks the package manager from the recorded base image
+	cfg := LoadConfig()
+	cfg.setProfileBaseImage(base.Name)
+	cfg.setProfileInstallDir(installDir)
+	if err := SaveConfig(cfg); err != nil {
+		return fmt.Errorf("failed to record distro settings: %w", err)
+	}
 	return nil
 }
 
-// InstallEngine installs a specific container engine inside the Ubuntu distro
+// InstallEngine installs a specific container engine inside the distro
 func InstallEngine(engine string) error {
+	return withLock(OperationLock, "installing "+engine, func() error {
+		return installEngine(engine)
+	})
+}
+
+func installEngine(engine string) error {
 	// 0. Ensure distro exists
-	if err := SetupDistro(); err != nil {
+	if err := setupDistro(SetupOptions{}); err != nil {
 		return err
 	}
 
 	var setupCmd string
-	switch engine {
-	case "docker":
-		setupCmd = "apt-get update && apt-get install -y docker.io"
-	case "podman":
-		setupCmd = "apt-get update && apt-get install -y podman"
-	case "k3s":
-		setupCmd = "apt-get update && apt-get install -y curl && curl -sfL https://get.k3s.io | INSTALL_K3S_SKIP_ENABLE=true sh -"
-	case "nerdctl":
-		setupCmd = "apt-get update && apt-get install -y containerd wget tar && wget -q https://github.com/containerd/nerdctl/releases/download/v1.7.3/nerdctl-1.7.3-linux-amd64.tar.gz -O /tmp/nerdctl.tar.gz && tar -C /usr/local/bin -xzf /tmp/nerdctl.tar.gz"
-	case "k3d":
-		setupCmd = "curl -s https://raw.githubusercontent.com/k3d-io/k3d/main/install.sh | bash"
-	default:
-		return fmt.Errorf("unknown engine: %s", engine)
+	cfg := LoadConfig()
+	base := CurrentBaseImage(cfg)
+
+	// Re-installing upgrades the engine, which may break existing containers
+	if snapshotsEnabled(cfg) && GetEngineStatus(engine).Version != "Not Installed" {
+		if err := takeSnapshot("upgrading " + engine); err != nil {
+			return err
+		}
+	}
+
+	// Prefer the artifact cache, so installs work without internet access
+	cache := GetCacheDir(cfg)
+	if m, err := LoadBundleManifest(cache); err == nil && m.HasEngine(engine) && m.BaseImage().PackageManager == base.PackageManager {
+		if err := m.Verify(cache, engineArtifactPrefixes(engine, base)...); err != nil {
+			return err
+		}
+		fmt.Printf("Installing %s from the artifact cache...\n", engine)
+		setupCmd = offlineInstallCommand(engine, cache, base)
+	}
+
+	if setupCmd == "" {
+		cmd, err := base.onlineInstallCommand(engine)
+		if err != nil {
+			return err
+		}
+		setupCmd = cmd
 	}
 
 	cmd := exec.Command("wsl", "-d", DistroName, "-u", "root", "sh", "-c", setupCmd)
@@ -86,32 +227,16 @@ func InstallEngine(engine string) error {
 	}
 
 	// Start engine automatically
-	if err := EnsureEngineRunning(engine); err != nil {
+	if err := ensureEngineRunning(engine); err != nil {
 		return fmt.Errorf("installed but failed to start %s: %w", engine, err)
 	}
 
 	return nil
 }
 
-func downloadFile(url string, filepath string) error {
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	out, err := os.Create(filepath)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
-	return err
-}
-
 // StopEngine stops an engine's daemon inside WSL
 func StopEngine(engine string) error {
+	InvalidateEngineReady("")
 	cmd := exec.Command("wsl", "-d", DistroName, "-u", "root", "service", engine, "stop")
 	return cmd.Run()
 }
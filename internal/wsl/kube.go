@@ -0,0 +1,154 @@
+package wsl
+
+import (
+	"regexp"
+	"strings"
+)
+
+// kubePathFlags are kubectl/helm options whose value is a local file or directory
+var kubePathFlags = map[string]bool{
+	"-f": true, "--filename": true, "--values": true,
+	"-k": true, "--kustomize": true,
+	"--kubeconfig": true, "--cache-dir": true,
+	"--certificate-authority": true, "--client-certificate": true, "--client-key": true,
+	"--ca-file": true, "--cert-file": true, "--key-file": true,
+	"--repository-config": true, "--repository-cache": true, "--registry-config": true,
+	"-d": true, "--destination": true, "--untardir": true,
+}
+
+// kubeKeyedPathFlags take key=path values (possibly comma-separated for --set-file)
+var kubeKeyedPathFlags = map[string]bool{
+	"--from-file": true, "--from-env-file": true, "--set-file": true,
+}
+
+// kubeValueFlags take a value that is not a local path
+var kubeValueFlags = map[string]bool{
+	"-n": true, "--namespace": true, "-c": true, "--container": true, "--context": true,
+	"--cluster": true, "--user": true, "-l": true, "--selector": true, "-o": true, "--output": true,
+	"--retries": true, "-s": true, "--server": true, "--token": true, "--as": true,
+	"--request-timeout": true, "--kube-context": true, "--version": true, "--set": true,
+	"--set-string": true, "--timeout": true, "--repo": true,
+}
+
+// helmChartCommands take a chart reference as positional argument
+var helmChartCommands = map[string]bool{
+	"install": true, "upgrade": true, "template": true, "lint": true, "package": true,
+	"dependency": true, "dep": true, "show": true, "inspect": true,
+}
+
+var windowsDrivePath = regexp.MustCompile(`^[A-Za-z]:[\\/]`)
+
+// translateLocalPath converts a local path argument for use inside the distro.
+// Unlike TranslatePath it also accepts C:/ style paths and relative paths with backslashes.
+func translateLocalPath(p string) string {
+	if p == "-" || strings.Contains(p, "://") {
+		return p
+	}
+	if windowsDrivePath.MatchString(p) {
+		return "/mnt/" + strings.ToLower(p[:1]) + "/" + strings.ReplaceAll(p[3:], "\\", "/")
+	}
+	return strings.ReplaceAll(p, "\\", "/")
+}
+
+// translateKeyedPath converts the path part of key=path values ("cfg=C:\app.ini", "C:\app.ini")
+func translateKeyedPath(v string, multi bool) string {
+	parts := []string{v}
+	if multi {
+		parts = strings.Split(v, ",")
+	}
+	for i, part := range parts {
+		if windowsDrivePath.MatchString(part) {
+			parts[i] = translateLocalPath(part)
+			continue
+		}
+		if key, path, ok := strings.Cut(part, "="); ok {
+			parts[i] = key + "=" + translateLocalPath(path)
+		} else {
+			parts[i] = translateLocalPath(part)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// isLocalPathArg reports whether a positional argument clearly refers to a local path
+func isLocalPathArg(arg string) bool {
+	return windowsDrivePath.MatchString(arg) || strings.HasPrefix(arg, ".") || strings.Contains(arg, "\\")
+}
+
+// isKubeRemote reports whether a kubectl cp operand names a pod side ([namespace/]pod:path)
+func isKubeRemote(arg string) bool {
+	return !windowsDrivePath.MatchString(arg) && strings.Contains(arg, ":")
+}
+
+// TranslateKubeArgs translates the local paths of a kubectl or helm invocation: file flags
+// (-f, -k, --kubeconfig, --values, ...), the local operand of 'kubectl cp' and local chart
+// directories. Other arguments that look like Windows paths get the generic translation.
+// Pod-side paths and everything after "--" are left untouched.
+func TranslateKubeArgs(tool string, args []string) []string {
+	translated := make([]string, len(args))
+	copy(translated, args)
+
+	subcommand := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break // the rest runs inside the pod
+		}
+
+		if strings.HasPrefix(arg, "-") {
+			name, value, hasValue := strings.Cut(arg, "=")
+			switch {
+			case kubePathFlags[name] && (name != "-d" || tool == "helm"):
+				if hasValue {
+					translated[i] = name + "=" + translateLocalPath(value)
+				} else if i+1 < len(args) {
+					i++
+					translated[i] = translateLocalPath(args[i])
+				}
+			case kubeKeyedPathFlags[name]:
+				if hasValue {
+					translated[i] = name + "=" + translateKeyedPath(value, name == "--set-file")
+				} else if i+1 < len(args) {
+					i++
+					translated[i] = translateKeyedPath(args[i], name == "--set-file")
+				}
+			case kubeValueFlags[name]:
+				if !hasValue {
+					i++
+				}
+			default:
+				// Other flags (--cert C:\x.crt, --cert=C:\x.crt) get the generic heuristic,
+				// and so does their value, which the loop sees as a positional argument
+				translated[i] = translateArg(arg)
+			}
+			continue
+		}
+
+		if subcommand == "" {
+			subcommand = arg
+			continue
+		}
+
+		switch {
+		case tool == "kubectl" && subcommand == "cp":
+			if !isKubeRemote(arg) {
+				translated[i] = translateLocalPath(arg)
+			}
+		case tool == "helm" && helmChartCommands[subcommand] && isLocalPathArg(arg):
+			translated[i] = translateLocalPath(arg)
+		default:
+			translated[i] = translateArg(arg)
+		}
+	}
+
+	return translated
+}
+
+// translateToolArgs picks the argument translation for a proxied tool
+func translateToolArgs(tool string, args []string) []string {
+	switch tool {
+	case "kubectl", "helm":
+		return TranslateKubeArgs(tool, args)
+	}
+	return TranslateArgs(args)
+}
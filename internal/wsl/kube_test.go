@@ -0,0 +1,39 @@
+package wsl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTranslateKubeArgs(t *testing.T) {
+	tests := []struct {
+		tool string
+		args []string
+		want []string
+	}{
+		{"kubectl", []string{"apply", "-f", `C:\dir\x.yaml`}, []string{"apply", "-f", "/mnt/c/dir/x.yaml"}},
+		{"kubectl", []string{"apply", `--filename=D:\k8s`}, []string{"apply", "--filename=/mnt/d/k8s"}},
+		{"kubectl", []string{"apply", "-k", `.\overlays\dev`}, []string{"apply", "-k", "./overlays/dev"}},
+		{"kubectl", []string{"apply", "-f", "https://example.com/x.yaml"}, []string{"apply", "-f", "https://example.com/x.yaml"}},
+		{"kubectl", []string{"--kubeconfig", `C:\Users\me\.kube\config`, "get", "pods"}, []string{"--kubeconfig", "/mnt/c/Users/me/.kube/config", "get", "pods"}},
+		{"kubectl", []string{"cp", `C:\file`, "web-0:/x"}, []string{"cp", "/mnt/c/file", "web-0:/x"}},
+		{"kubectl", []string{"cp", "-n", "prod", "prod/web-0:/var/log/app.log", `C:/logs/app.log`}, []string{"cp", "-n", "prod", "prod/web-0:/var/log/app.log", "/mnt/c/logs/app.log"}},
+		{"kubectl", []string{"cp", `C:\x`, `win-pod:C:\app`}, []string{"cp", "/mnt/c/x", `win-pod:C:\app`}},
+		{"kubectl", []string{"exec", "web-0", "--", "cat", `C:\inside`}, []string{"exec", "web-0", "--", "cat", `C:\inside`}},
+		{"kubectl", []string{"create", "configmap", "app", `--from-file=app.ini=C:\cfg\app.ini`}, []string{"create", "configmap", "app", "--from-file=app.ini=/mnt/c/cfg/app.ini"}},
+		{"kubectl", []string{"get", "-n", "C:", "pods"}, []string{"get", "-n", "C:", "pods"}},
+		{"kubectl", []string{"create", "secret", "tls", "web", "--cert", `C:\x.crt`, `--key=C:\x.key`}, []string{"create", "secret", "tls", "web", "--cert", "/mnt/c/x.crt", "--key=/mnt/c/x.key"}},
+		{"kubectl", []string{"kustomize", `C:\dir`}, []string{"kustomize", "/mnt/c/dir"}},
+		{"helm", []string{"install", "web", "./chart", "--values", `C:\v.yaml`}, []string{"install", "web", "./chart", "--values", "/mnt/c/v.yaml"}},
+		{"helm", []string{"upgrade", "web", `.\charts\web`, "-f", `D:\vals.yaml`}, []string{"upgrade", "web", "./charts/web", "-f", "/mnt/d/vals.yaml"}},
+		{"helm", []string{"install", "db", "bitnami/postgresql"}, []string{"install", "db", "bitnami/postgresql"}},
+		{"helm", []string{"install", "web", "chart", `--set-file`, `a=C:\a.txt,b=.\b.txt`}, []string{"install", "web", "chart", "--set-file", "a=/mnt/c/a.txt,b=./b.txt"}},
+	}
+
+	for _, tt := range tests {
+		got := TranslateKubeArgs(tt.tool, tt.args)
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("TranslateKubeArgs(%s, %v) = %v; want %v", tt.tool, tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package wsl
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// KubeContextPrefix marks the contexts k3d (run through ezship) adds to a kubeconfig
+const KubeContextPrefix = "k3d-"
+
+var (
+	kubeNameLine    = regexp.MustCompile(`^(?:- |  )name:\s*(.+)$`)
+	kubeRefLine     = regexp.MustCompile(`^    (cluster|user):\s*(.+)$`)
+	kubeCurrentLine = regexp.MustCompile(`^current-context:\s*(.*)$`)
+)
+
+// kubeBlock is a run of kubeconfig lines: an entry of the clusters, contexts or users list,
+// or anything else (kept as is)
+type kubeBlock struct {
+	section       string // "clusters", "contexts", "users" for list entries
+	name          string
+	cluster, user string // contexts only
+	lines         []string
+}
+
+// kubeconfigPaths returns the host kubeconfig files: those in KUBECONFIG, or ~/.kube/config
+func kubeconfigPaths() []string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		var paths []string
+		for _, p := range filepath.SplitList(env) {
+			if p != "" {
+				paths = append(paths, p)
+			}
+		}
+		return paths
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(home, ".kube", "config")}
+}
+
+func unquote(s string) string {
+	return strings.Trim(strings.TrimSpace(s), `"'`)
+}
+
+// parseKubeconfig splits a kubeconfig in the layout kubectl and k3d write into blocks
+func parseKubeconfig(data string) []kubeBlock {
+	var blocks []kubeBlock
+	section := ""
+	for _, line := range strings.Split(data, "\n") {
+		switch {
+		case strings.HasPrefix(line, "- ") && section != "":
+			blocks = append(blocks, kubeBlock{section: section})
+		case line != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "-"):
+			// A top-level key ends the previous list
+			section = ""
+			if key := strings.TrimSuffix(strings.TrimSpace(line), ":"); key == "clusters" || key == "contexts" || key == "users" {
+				section = key
+			}
+			blocks = append(blocks, kubeBlock{})
+		case len(blocks) == 0:
+			blocks = append(blocks, kubeBlock{})
+		}
+		b := &blocks[len(blocks)-1]
+		b.lines = append(b.lines, line)
+		if b.section == "" {
+			continue
+		}
+		if m := kubeNameLine.FindStringSubmatch(line); m != nil {
+			b.name = unquote(m[1])
+		} else if m := kubeRefLine.FindStringSubmatch(line); m != nil && b.section == "contexts" {
+			if m[1] == "cluster" {
+				b.cluster = unquote(m[2])
+			} else {
+				b.user = unquote(m[2])
+			}
+		}
+	}
+	return blocks
+}
+
+// ezshipKubeContexts returns the contexts of a kubeconfig that k3d created for the given clusters
+// (those inside ezship's distros). Other "k3d-" contexts, e.g. from a k3d on Docker Desktop, stay.
+func ezshipKubeContexts(data string, clusters []string) []string {
+	own := make(map[string]bool)
+	for _, c := range clusters {
+		own[KubeContextPrefix+c] = true
+	}
+	var names []string
+	for _, b := range parseKubeconfig(data) {
+		if b.section == "contexts" && own[b.name] && (b.cluster == "" || own[b.cluster]) {
+			names = append(names, b.name)
+		}
+	}
+	return names
+}
+
+// removeKubeContexts drops the contexts from a kubeconfig, with the clusters and users only
+// they refer to. A removed current-context is cleared.
+func removeKubeContexts(data string, contexts []string) string {
+	blocks := parseKubeconfig(data)
+	drop := make(map[string]bool)
+	for _, c := range contexts {
+		drop[c] = true
+	}
+
+	// Clusters and users stay while a remaining context still refers to them
+	clusters, users := make(map[string]bool), make(map[string]bool)
+	keepClusters, keepUsers := make(map[string]bool), make(map[string]bool)
+	for _, b := range blocks {
+		if b.section != "contexts" {
+			continue
+		}
+		if drop[b.name] {
+			clusters[b.cluster], users[b.user] = true, true
+		} else {
+			keepClusters[b.cluster], keepUsers[b.user] = true, true
+		}
+	}
+
+	var out []string
+	for _, b := range blocks {
+		switch b.section {
+		case "contexts":
+			if drop[b.name] {
+				continue
+			}
+		case "clusters":
+			if clusters[b.name] && !keepClusters[b.name] {
+				continue
+			}
+		case "users":
+			if users[b.name] && !keepUsers[b.name] {
+				continue
+			}
+		}
+		for _, line := range b.lines {
+			if m := kubeCurrentLine.FindStringSubmatch(line); m != nil && drop[unquote(m[1])] {
+				line = `current-context: ""`
+			}
+			out = append(out, line)
+		}
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,74 @@
+package wsl
+
+import (
+	"strings"
+	"testing"
+)
+
+const sampleKubeconfig = `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: AAAA
+    server: https://prod.example.com
+  name: prod
+- cluster:
+    certificate-authority-data: BBBB
+    server: https://0.0.0.0:6550
+  name: k3d-dev
+contexts:
+- context:
+    cluster: prod
+    user: prod-admin
+  name: prod
+- context:
+    cluster: k3d-dev
+    user: admin@k3d-dev
+  name: k3d-dev
+current-context: k3d-dev
+kind: Config
+preferences: {}
+users:
+- name: prod-admin
+  user:
+    token: secret
+- name: admin@k3d-dev
+  user:
+    client-certificate-data: CCCC
+`
+
+func TestEzshipKubeContexts(t *testing.T) {
+	got := ezshipKubeContexts(sampleKubeconfig, []string{"dev", "other"})
+	if len(got) != 1 || got[0] != "k3d-dev" {
+		t.Errorf("ezshipKubeContexts() = %v; want [k3d-dev]", got)
+	}
+	// A k3d cluster ezship does not run (e.g. on Docker Desktop) is not ezship's
+	if got := ezshipKubeContexts(sampleKubeconfig, []string{"other"}); len(got) != 0 {
+		t.Errorf("Expected no contexts for other clusters, got %v", got)
+	}
+}
+
+func TestRemoveKubeContexts(t *testing.T) {
+	out := removeKubeContexts(sampleKubeconfig, []string{"k3d-dev"})
+	for _, gone := range []string{"k3d-dev", "BBBB", "CCCC", "6550"} {
+		if strings.Contains(out, gone) {
+			t.Errorf("Expected %q to be removed:\n%s", gone, out)
+		}
+	}
+	for _, kept := range []string{"name: prod", "prod-admin", "token: secret", "AAAA", `current-context: ""`, "kind: Config"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("Expected %q to be kept:\n%s", kept, out)
+		}
+	}
+	if again := removeKubeContexts(out, []string{"k3d-dev"}); again != out {
+		t.Error("Expected removal to be idempotent")
+	}
+}
+
+func TestRemoveKubeContextsSharedCluster(t *testing.T) {
+	// A cluster still used by another context stays
+	cfg := strings.Replace(sampleKubeconfig, "    cluster: prod\n", "    cluster: k3d-dev\n", 1)
+	out := removeKubeContexts(cfg, []string{"k3d-dev"})
+	if !strings.Contains(out, "name: k3d-dev\ncontexts:") {
+		t.Errorf("Expected the shared cluster to be kept:\n%s", out)
+	}
+}
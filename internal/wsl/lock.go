@@ -0,0 +1,105 @@
+package wsl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// OperationLock guards distro-wide operations (engine startup, setup, install, vacuum)
+	OperationLock = "operation"
+
+	lockPollInterval = 250 * time.Millisecond
+	lockTimeout      = 15 * time.Minute
+)
+
+// Lock is an inter-process lock backed by a file in the state directory
+type Lock struct {
+	file *os.File
+	name string
+}
+
+func lockPath(name string) string {
+	return filepath.Join(GetStateDir(), name+".lock")
+}
+
+// TryAcquireLock takes the named lock without waiting. It returns nil if another process holds it.
+func TryAcquireLock(name, desc string) (*Lock, error) {
+	path := lockPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	ok, err := tryLockFile(f)
+	if err != nil || !ok {
+		f.Close()
+		return nil, err
+	}
+
+	// The holder description lives next to the lock so waiters can read it
+	os.WriteFile(path+".info", []byte(fmt.Sprintf("%s (pid %d)", desc, os.Getpid())), 0644)
+	return &Lock{file: f, name: name}, nil
+}
+
+// AcquireLock takes the named lock, waiting for any other ezship process that holds it
+func AcquireLock(name, desc string) (*Lock, error) {
+	deadline := time.Now().Add(lockTimeout)
+	announced := false
+	for {
+		l, err := TryAcquireLock(name, desc)
+		if err != nil {
+			return nil, err
+		}
+		if l != nil {
+			return l, nil
+		}
+
+		if !announced {
+			// Progress goes to stderr so it never mixes with proxied stdout
+			fmt.Fprintf(os.Stderr, "Another ezship operation is in progress (%s), waiting...\n", LockHolder(name))
+			announced = true
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for another ezship operation (%s)", LockHolder(name))
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// LockHolder describes the process currently holding the named lock, if known
+func LockHolder(name string) string {
+	data, err := os.ReadFile(lockPath(name) + ".info")
+	if err != nil || len(data) == 0 {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Release frees the lock
+func (l *Lock) Release() {
+	if l == nil || l.file == nil {
+		return
+	}
+	os.Remove(lockPath(l.name) + ".info")
+	unlockFile(l.file)
+	l.file.Close()
+	l.file = nil
+}
+
+// withLock runs fn while holding the named lock
+func withLock(name, desc string, fn func() error) error {
+	l, err := AcquireLock(name, desc)
+	if err != nil {
+		return err
+	}
+	defer l.Release()
+	return fn()
+}
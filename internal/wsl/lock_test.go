@@ -0,0 +1,34 @@
+package wsl
+
+import (
+	"testing"
+)
+
+func TestLockExclusive(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+
+	first, err := TryAcquireLock("test", "first holder")
+	if err != nil || first == nil {
+		t.Fatalf("Expected to acquire free lock, got %v", err)
+	}
+
+	second, err := TryAcquireLock("test", "second holder")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if second != nil {
+		t.Fatal("Expected lock to be busy while held")
+	}
+
+	if holder := LockHolder("test"); holder == "unknown" {
+		t.Error("Expected holder description to be recorded")
+	}
+
+	first.Release()
+
+	third, err := TryAcquireLock("test", "third holder")
+	if err != nil || third == nil {
+		t.Fatalf("Expected to acquire released lock, got %v", err)
+	}
+	third.Release()
+}
@@ -0,0 +1,69 @@
+package wsl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MoveDistro relocates the active profile's distro to dir (e.g. another drive): it exports the
+// distro next to the destination, unregisters it and re-imports it there, then records the
+// new location in the config.
+func MoveDistro(dir string) error {
+	return withLock(OperationLock, "moving distro", func() error {
+		return moveDistro(dir)
+	})
+}
+
+func moveDistro(dir string) error {
+	newDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid install directory: %w", err)
+	}
+	cfg := LoadConfig()
+	oldDir := ProfileInstallDir(activeProfile, cfg)
+	if strings.EqualFold(filepath.Clean(newDir), filepath.Clean(oldDir)) {
+		return fmt.Errorf("%s already lives in %s", DistroName, oldDir)
+	}
+	if installed, _ := IsDistroInstalled(); !installed {
+		return fmt.Errorf("distro %s is not installed", DistroName)
+	}
+	if _, err := os.Stat(filepath.Join(newDir, "ext4.vhdx")); err == nil {
+		return fmt.Errorf("%s already holds a WSL disk", newDir)
+	}
+	if err := os.MkdirAll(newDir, 0755); err != nil {
+		return fmt.Errorf("failed to create install directory: %w", err)
+	}
+
+	// Export onto the destination drive, which is the one known to have room
+	InvalidateEngineReady("")
+	wslCommand("--terminate", DistroName).Run()
+	tarPath := filepath.Join(newDir, DistroName+"-move.tar")
+	fmt.Printf("Exporting %s to %s...\n", DistroName, tarPath)
+	if output, err := wslCommand("--export", DistroName, tarPath).CombinedOutput(); err != nil {
+		os.Remove(tarPath)
+		return fmt.Errorf("failed to export distro: %s (%w)", strings.TrimSpace(string(output)), err)
+	}
+
+	if err := unregisterDistro(); err != nil {
+		os.Remove(tarPath)
+		return err
+	}
+
+	fmt.Printf("Importing %s into %s...\n", DistroName, newDir)
+	base := CurrentBaseImage(cfg)
+	if err := importDistro(newDir, tarPath, base); err != nil {
+		// Put it back where it was; keep the export if even that fails
+		if restoreErr := importDistro(oldDir, tarPath, base); restoreErr != nil {
+			return fmt.Errorf("%w; restoring the old location also failed, the distro is saved in %s", err, tarPath)
+		}
+		os.Remove(tarPath)
+		return fmt.Errorf("%w (the distro was restored to %s)", err, oldDir)
+	}
+
+	os.Remove(tarPath)
+	// The old directory only held the disk, which the unregister removed
+	os.Remove(oldDir)
+	return nil
+}
@@ -0,0 +1,66 @@
+package wsl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMoveDistro(t *testing.T) {
+	calls := stubDistroBackend(t, "ezship\r\n")
+	newDir := filepath.Join(t.TempDir(), "D", "ezship")
+
+	if err := MoveDistro(GetInstallDir()); err == nil || !strings.Contains(err.Error(), "already lives") {
+		t.Errorf("Expected error when moving to the current location, got %v", err)
+	}
+
+	if err := MoveDistro(newDir); err != nil {
+		t.Fatalf("MoveDistro failed: %v", err)
+	}
+
+	var ops []string
+	for _, c := range calls.Args() {
+		if c[0] != "--list" {
+			ops = append(ops, c[0])
+		}
+	}
+	if got := strings.Join(ops, " "); got != "--terminate --export --unregister --import" {
+		t.Errorf("Unexpected wsl calls: %s", got)
+	}
+	args := calls.Args()
+	last := args[len(args)-1]
+	if last[2] != newDir || filepath.Dir(last[3]) != newDir {
+		t.Errorf("Expected import into %s from an export stored there, got %v", newDir, last)
+	}
+
+	if GetInstallDir() != newDir || LoadConfig().InstallDir != newDir {
+		t.Errorf("Expected the new location to be persisted, got %s", GetInstallDir())
+	}
+	if _, err := os.Stat(last[3]); !os.IsNotExist(err) {
+		t.Error("Expected the temporary export to be removed")
+	}
+}
+
+func TestMoveDistroRefusesOccupiedDir(t *testing.T) {
+	stubDistroBackend(t, "ezship\r\n")
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "ext4.vhdx"), []byte("other"), 0644)
+
+	if err := MoveDistro(dir); err == nil {
+		t.Error("Expected error when the destination already holds a disk")
+	}
+}
+
+func TestSetProfileInstallDir(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	var cfg Config
+	cfg.setProfileInstallDir(defaultInstallDir(DefaultProfile))
+	if cfg.InstallDir != "" {
+		t.Errorf("Expected the default location not to be recorded, got %s", cfg.InstallDir)
+	}
+	cfg.setProfileInstallDir(`D:\ezship`)
+	if ProfileInstallDir(DefaultProfile, cfg) != `D:\ezship` {
+		t.Errorf("Expected the recorded location, got %s", ProfileInstallDir(DefaultProfile, cfg))
+	}
+}
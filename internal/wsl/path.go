@@ -24,12 +24,16 @@ func TranslatePath(input string) string {
 func TranslateArgs(args []string) []string {
 	translated := make([]string, len(args))
 	for i, arg := range args {
-		// Basic heuristic: if it contains ":\" or starts with a drive letter and has backslashes
-		if strings.Contains(arg, ":\\") || (len(arg) >= 3 && arg[1] == ':' && strings.Contains(arg, "\\")) {
-			translated[i] = TranslatePath(arg)
-		} else {
-			translated[i] = arg
-		}
+		translated[i] = translateArg(arg)
 	}
 	return translated
 }
+
+// translateArg translates a single argument if it looks like a Windows path
+func translateArg(arg string) string {
+	// Basic heuristic: if it contains ":\" or starts with a drive letter and has backslashes
+	if strings.Contains(arg, ":\\") || (len(arg) >= 3 && arg[1] == ':' && strings.Contains(arg, "\\")) {
+		return TranslatePath(arg)
+	}
+	return arg
+}
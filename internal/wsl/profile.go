@@ -0,0 +1,271 @@
+package wsl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// DefaultProfile is the environment that existed before profiles: the "ezship" distro
+const DefaultProfile = "default"
+
+// ProfileEnvVar selects the profile for a single shell or proxied command
+const ProfileEnvVar = "EZSHIP_PROFILE"
+
+// activeProfile is the profile whose distro every operation targets (see UseProfile)
+var activeProfile = DefaultProfile
+
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// ProfileConfig is the config section of a named profile
+type ProfileConfig struct {
+	Created time.Time `json:"created"`
+	// BaseImage is the base distribution the profile's distro was created from
+	BaseImage string `json:"base_image,omitempty"`
+	// InstallDir is where the profile's virtual disk lives, if not the default
+	InstallDir string `json:"install_dir,omitempty"`
+}
+
+// ProfileInfo describes a profile for 'ezship env list'
+type ProfileInfo struct {
+	Name       string
+	Distro     string
+	InstallDir string
+	BaseImage  string
+	Default    bool
+	Active     bool
+}
+
+// ValidateProfileName checks that a profile name can be used in a WSL distro name
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q (use up to 32 lowercase letters, digits and dashes)", name)
+	}
+	return nil
+}
+
+// ProfileDistroName returns the WSL distro backing a profile
+func ProfileDistroName(profile string) string {
+	if profile == DefaultProfile {
+		return "ezship"
+	}
+	return "ezship-" + profile
+}
+
+// ProfileInstallDir returns the directory holding a profile's virtual disk: the location
+// recorded in the config (see 'ezship move'), or the default under %APPDATA%
+func ProfileInstallDir(profile string, cfg Config) string {
+	dir := cfg.InstallDir
+	if profile != DefaultProfile {
+		dir = cfg.Profiles[profile].InstallDir
+	}
+	if dir != "" {
+		return dir
+	}
+	return defaultInstallDir(profile)
+}
+
+// defaultInstallDir is the install directory used when none is configured
+func defaultInstallDir(profile string) string {
+	appData := os.Getenv("APPDATA")
+	if profile == DefaultProfile {
+		return filepath.Join(appData, "ezship")
+	}
+	return filepath.Join(appData, "ezship", "profiles", profile)
+}
+
+// GetInstallDir returns the install directory of the active profile's distro
+func GetInstallDir() string {
+	return ProfileInstallDir(activeProfile, LoadConfig())
+}
+
+// ActiveProfile returns the profile operations currently target
+func ActiveProfile() string {
+	return activeProfile
+}
+
+// ResolveProfile picks the profile to use: an explicit name (the --profile flag),
+// then EZSHIP_PROFILE, then default_profile from the config
+func ResolveProfile(explicit string, cfg Config) string {
+	if explicit != "" {
+		return explicit
+	}
+	if env := os.Getenv(ProfileEnvVar); env != "" {
+		return env
+	}
+	if cfg.DefaultProfile != "" {
+		return cfg.DefaultProfile
+	}
+	return DefaultProfile
+}
+
+// UseProfile makes a profile the target of all following operations
+func UseProfile(name string, cfg Config) error {
+	if name != DefaultProfile {
+		if err := ValidateProfileName(name); err != nil {
+			return err
+		}
+		if _, ok := cfg.Profiles[name]; !ok {
+			return fmt.Errorf("unknown profile %q (create it with 'ezship env create %s')", name, name)
+		}
+	}
+	activeProfile = name
+	DistroName = ProfileDistroName(name)
+	return nil
+}
+
+// CreateProfile adds a profile section to the config; the distro is set up separately
+func CreateProfile(name, base string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("the %q profile always exists", DefaultProfile)
+	}
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+	if base != "" {
+		if _, err := GetBaseImage(base); err != nil {
+			return err
+		}
+	}
+
+	cfg := LoadConfig()
+	if _, ok := cfg.Profiles[name]; ok {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]ProfileConfig)
+	}
+	cfg.Profiles[name] = ProfileConfig{Created: time.Now().UTC(), BaseImage: base}
+	return SaveConfig(cfg)
+}
+
+// DeleteProfile unregisters a profile's distro and removes its config section
+func DeleteProfile(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("the %q profile cannot be removed (use 'ezship reset')", DefaultProfile)
+	}
+	cfg := LoadConfig()
+	if _, ok := cfg.Profiles[name]; !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+
+	previous := activeProfile
+	if err := UseProfile(name, cfg); err != nil {
+		return err
+	}
+	defer UseProfile(previous, cfg)
+
+	err := withLock(OperationLock, "removing profile "+name, func() error {
+		if installed, _ := IsDistroInstalled(); installed {
+			if err := ResetDistro(); err != nil {
+				return err
+			}
+		}
+		// Unregistering deletes the disk; only remove the directory if nothing else is in it
+		os.Remove(ProfileInstallDir(name, cfg))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg = LoadConfig()
+	delete(cfg.Profiles, name)
+	if cfg.DefaultProfile == name {
+		cfg.DefaultProfile = ""
+	}
+	return SaveConfig(cfg)
+}
+
+// SetDefaultProfile makes a profile the one used when neither --profile nor EZSHIP_PROFILE is set
+func SetDefaultProfile(name string) error {
+	cfg := LoadConfig()
+	if name != DefaultProfile {
+		if _, ok := cfg.Profiles[name]; !ok {
+			return fmt.Errorf("unknown profile %q", name)
+		}
+	}
+	cfg.DefaultProfile = name
+	if name == DefaultProfile {
+		cfg.DefaultProfile = ""
+	}
+	return SaveConfig(cfg)
+}
+
+// ListProfiles returns the default profile followed by the named ones, sorted
+func ListProfiles(cfg Config) []ProfileInfo {
+	defaultName := cfg.DefaultProfile
+	if defaultName == "" {
+		defaultName = DefaultProfile
+	}
+
+	names := []string{DefaultProfile}
+	var named []string
+	for name := range cfg.Profiles {
+		named = append(named, name)
+	}
+	sort.Strings(named)
+	names = append(names, named...)
+
+	infos := make([]ProfileInfo, 0, len(names))
+	for _, name := range names {
+		base := cfg.BaseImage
+		if name != DefaultProfile {
+			base = cfg.Profiles[name].BaseImage
+		}
+		if base == "" {
+			base = DefaultBaseImage
+		}
+		infos = append(infos, ProfileInfo{
+			Name:       name,
+			Distro:     ProfileDistroName(name),
+			InstallDir: ProfileInstallDir(name, cfg),
+			BaseImage:  base,
+			Default:    name == defaultName,
+			Active:     name == activeProfile,
+		})
+	}
+	return infos
+}
+
+// profileBaseImage returns the base image recorded for the active profile
+func (cfg Config) profileBaseImage() string {
+	if activeProfile == DefaultProfile {
+		return cfg.BaseImage
+	}
+	return cfg.Profiles[activeProfile].BaseImage
+}
+
+// setProfileBaseImage records the base image of the active profile
+func (cfg *Config) setProfileBaseImage(name string) {
+	if activeProfile == DefaultProfile {
+		cfg.BaseImage = name
+		return
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]ProfileConfig)
+	}
+	p := cfg.Profiles[activeProfile]
+	p.BaseImage = name
+	cfg.Profiles[activeProfile] = p
+}
+
+// setProfileInstallDir records the install directory of the active profile ("" for the default)
+func (cfg *Config) setProfileInstallDir(dir string) {
+	if filepath.Clean(dir) == filepath.Clean(defaultInstallDir(activeProfile)) {
+		dir = ""
+	}
+	if activeProfile == DefaultProfile {
+		cfg.InstallDir = dir
+		return
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]ProfileConfig)
+	}
+	p := cfg.Profiles[activeProfile]
+	p.InstallDir = dir
+	cfg.Profiles[activeProfile] = p
+}
@@ -0,0 +1,98 @@
+package wsl
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveProfile(t *testing.T) {
+	cfg := Config{DefaultProfile: "personal"}
+
+	t.Setenv(ProfileEnvVar, "")
+	if got := ResolveProfile("", Config{}); got != DefaultProfile {
+		t.Errorf("ResolveProfile with nothing set = %s; want %s", got, DefaultProfile)
+	}
+	if got := ResolveProfile("", cfg); got != "personal" {
+		t.Errorf("ResolveProfile from config = %s; want personal", got)
+	}
+
+	t.Setenv(ProfileEnvVar, "client")
+	if got := ResolveProfile("", cfg); got != "client" {
+		t.Errorf("ResolveProfile from env = %s; want client", got)
+	}
+	if got := ResolveProfile("work-k8s", cfg); got != "work-k8s" {
+		t.Errorf("ResolveProfile from flag = %s; want work-k8s", got)
+	}
+}
+
+func TestUseProfile(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	t.Cleanup(func() { UseProfile(DefaultProfile, Config{}) })
+
+	cfg := Config{
+		BaseImage: "debian-12",
+		Profiles:  map[string]ProfileConfig{"work-k8s": {BaseImage: "alpine"}},
+	}
+
+	if err := UseProfile("missing", cfg); err == nil {
+		t.Error("Expected error for a profile that was never created")
+	}
+	if err := UseProfile("Bad_Name", cfg); err == nil {
+		t.Error("Expected error for an invalid profile name")
+	}
+
+	if err := UseProfile("work-k8s", cfg); err != nil {
+		t.Fatalf("UseProfile failed: %v", err)
+	}
+	if DistroName != "ezship-work-k8s" || ActiveProfile() != "work-k8s" {
+		t.Errorf("Expected the work-k8s distro to be active, got %s (%s)", DistroName, ActiveProfile())
+	}
+	if want := filepath.Join(ProfileInstallDir(DefaultProfile, cfg), "profiles", "work-k8s"); GetInstallDir() != want {
+		t.Errorf("GetInstallDir() = %s; want %s", GetInstallDir(), want)
+	}
+	if got := CurrentBaseImage(cfg).Name; got != "alpine" {
+		t.Errorf("Expected the profile's own base image, got %s", got)
+	}
+
+	// Recording a base image only touches the active profile's section
+	cfg.setProfileBaseImage("ubuntu-22.04")
+	if cfg.BaseImage != "debian-12" || cfg.Profiles["work-k8s"].BaseImage != "ubuntu-22.04" {
+		t.Errorf("Unexpected config after setProfileBaseImage: %+v", cfg)
+	}
+
+	if err := UseProfile(DefaultProfile, cfg); err != nil {
+		t.Fatalf("UseProfile(default) failed: %v", err)
+	}
+	if DistroName != "ezship" || CurrentBaseImage(cfg).Name != "debian-12" {
+		t.Errorf("Expected the default profile to use the top-level settings, got %s", DistroName)
+	}
+}
+
+func TestProfileLifecycle(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+
+	if err := CreateProfile("work-k8s", "alpine"); err != nil {
+		t.Fatalf("CreateProfile failed: %v", err)
+	}
+	if err := CreateProfile("work-k8s", ""); err == nil {
+		t.Error("Expected error when creating a profile twice")
+	}
+	if err := CreateProfile(DefaultProfile, ""); err == nil {
+		t.Error("Expected error when creating the default profile")
+	}
+	if err := SetDefaultProfile("work-k8s"); err != nil {
+		t.Fatalf("SetDefaultProfile failed: %v", err)
+	}
+
+	profiles := ListProfiles(LoadConfig())
+	if len(profiles) != 2 || profiles[0].Name != DefaultProfile || profiles[1].Name != "work-k8s" {
+		t.Fatalf("Unexpected profiles: %+v", profiles)
+	}
+	if profiles[0].Default || !profiles[1].Default || profiles[1].BaseImage != "alpine" {
+		t.Errorf("Expected work-k8s to be the default alpine profile: %+v", profiles)
+	}
+
+	if err := SetDefaultProfile("nope"); err == nil {
+		t.Error("Expected error for an unknown default profile")
+	}
+}
@@ -5,86 +5,260 @@ import (
 	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 	"time"
 )
 
-const DistroName = "ezship"
+// DistroName is the WSL distro of the active profile (see UseProfile)
+var DistroName = "ezship"
 
 var Version = "0.3.3"
 
-// RunProxyCommand executes a command inside the ezship WSL distro
-func RunProxyCommand(engine string, args []string) error {
-	// Ensure engine is running before executing command
-	if err := EnsureEngineRunning(engine); err != nil {
-		return fmt.Errorf("failed to start engine %s: %w", engine, err)
+// defaultToolEngines maps proxied tools without their own daemon to the engine they need
+var defaultToolEngines = map[string]string{
+	"docker-compose": "docker",
+	"podman-compose": "podman",
+	"helm":           "k3s",
+	"crictl":         "k3s",
+	"ctr":            "nerdctl",
+	"dive":           "docker",
+	"buildctl":       "",
+	"skopeo":         "",
+}
+
+// ToolEngine returns the engine that must be running before tool is proxied ("" for none)
+func ToolEngine(tool string, cfg Config) string {
+	if engine, ok := cfg.ToolEngines[tool]; ok {
+		return engine
 	}
+	for _, a := range ProxyAliases {
+		if a == tool {
+			return tool
+		}
+	}
+	return defaultToolEngines[tool]
+}
 
-	translatedArgs := TranslateArgs(args)
+// wslCommand builds a wsl.exe invocation. Tests swap it for a stub backend.
+var wslCommand = func(args ...string) *exec.Cmd {
+	return exec.Command("wsl", args...)
+}
+
+// proxyStreams describes the Windows-side streams of a proxied command
+type proxyStreams struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+	console        bool // stdin and stdout are both attached to a console
+	inTerminal     bool // stdin is a console (nothing is piped in, so a retry sees the same input)
+	outTerminal    bool // stdout is a console (eligible for path rewriting)
+	errTerminal    bool // stderr is a console (eligible for path rewriting)
+}
+
+// RunProxyCommand executes a tool inside the ezship WSL distro, starting the engine it depends on
+func RunProxyCommand(tool string, args []string) error {
+	return runProxy(tool, args, proxyStreams{
+		stdin:       os.Stdin,
+		stdout:      os.Stdout,
+		stderr:      os.Stderr,
+		console:     isTerminal(os.Stdin) && isTerminal(os.Stdout),
+		inTerminal:  isTerminal(os.Stdin),
+		outTerminal: isTerminal(os.Stdout),
+		errTerminal: isTerminal(os.Stderr),
+	})
+}
 
-	// Build the WSL command: wsl -d ezship -e <engine> <args>
-	wslArgs := []string{"-d", DistroName, "-e", engine}
-	wslArgs = append(wslArgs, translatedArgs...)
+// runProxy wires the given streams straight to wsl.exe. Data is never buffered by line or
+// re-encoded, so binary streams such as 'docker save' or 'docker load' pass through intact.
+// When the streams are *os.File, exec hands the handles to wsl.exe without any copying.
+func runProxy(tool string, args []string, streams proxyStreams) error {
+	cfg := LoadConfig()
+	ttl := readyTTL(cfg)
+	engine := ToolEngine(tool, cfg)
 
-	cmd := exec.Command("wsl", wslArgs...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
+	// Fast path: skip the distro/daemon checks if the engine was verified recently
+	cached := false
+	if engine != "" {
+		cached = IsEngineReadyCached(engine, ttl)
+		if !cached {
+			if err := EnsureEngineRunning(engine); err != nil {
+				return fmt.Errorf("failed to start engine %s: %w", engine, err)
+			}
+			MarkEngineReady(engine)
+		}
+	}
 
-	err := cmd.Run()
+	// Compose files get a translated copy; their paths are mapped back in error messages
+	args, compose, err := prepareCompose(tool, args)
 	if err != nil {
-		return fmt.Errorf("failed to run %s in WSL: %w", engine, err)
+		return err
 	}
+	defer compose.Cleanup()
 
-	return nil
-}
+	translatedArgs := AdjustTTYArgs(tool, translateToolArgs(tool, args), streams.console)
 
-func EnsureEngineRunning(engine string) error {
-	// Pre-requisite: ensure distro exists
-	installed, err := IsDistroInstalled()
-	if err != nil || !installed {
-		if setupErr := SetupDistro(); setupErr != nil {
-			return fmt.Errorf("distro not installed and setup failed: %w", setupErr)
+	// Build the WSL command: wsl -d ezship [-u root] -e <tool> <args>. Tools run as the
+	// distro's default user unless their engine is root-only.
+	wslArgs := engineCommandArgs(engine, append([]string{tool}, translatedArgs...)...)
+
+	// Optional /mnt/c/... -> C:\... rewriting, only for text shown on a console. Interactive
+	// sessions keep the raw handles so wsl.exe can still allocate a TTY.
+	stdout, stderr := streams.stdout, streams.stderr
+	var rewriters []*textRewriter
+	if cfg.ReversePaths && !requestsTTY(tool, translatedArgs) {
+		if streams.outTerminal {
+			r := newPathRewriter(stdout)
+			rewriters = append(rewriters, r)
+			stdout = r
+		}
+		if streams.errTerminal {
+			r := newPathRewriter(stderr)
+			rewriters = append(rewriters, r)
+			stderr = r
+		}
+	}
+	if compose != nil {
+		r := newLineRewriter(stderr, compose.RestorePaths)
+		rewriters = append(rewriters, r)
+		stderr = r
+	}
+	defer func() {
+		// Outermost filters first, so their output reaches the inner ones before those flush
+		for i := len(rewriters) - 1; i >= 0; i-- {
+			rewriters[i].Flush()
+		}
+	}()
+
+	// A command failing on a stale readiness cache is re-run once, after the full check, if
+	// the daemon turns out to be down. Input piped in is used up by the first attempt, so such
+	// commands are never re-run.
+	canRetry := cached && streams.inTerminal
+	var errTail *tailWriter
+	started := time.Now()
+	cmd := wslCommand(wslArgs...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if canRetry && !streams.errTerminal {
+		// A console stderr must reach wsl.exe as is (colours, BuildKit progress); elsewhere
+		// its tail spares the daemon probe for ordinary failures
+		errTail = &tailWriter{w: stderr, max: 4096}
+		cmd.Stderr = errTail
+	}
+	cmd.Stdin = streams.stdin
+	cmd.Env = ProxyEnv(tool, cfg, os.Environ())
+
+	err = cmd.Run()
+	if err != nil && cached && !canRetry {
+		// Cannot re-run it: have the next command check the engine
+		InvalidateEngineReady(engine)
+	}
+	if err != nil && canRetry && (errTail == nil || isConnectionError(errTail.String())) && !daemonUp(engine) {
+		// The cached state was stale: run the full check and retry once
+		InvalidateEngineReady(engine)
+		if startErr := EnsureEngineRunning(engine); startErr != nil {
+			return fmt.Errorf("failed to start engine %s: %w", engine, startErr)
 		}
+		MarkEngineReady(engine)
+
+		cmd = wslCommand(wslArgs...)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		cmd.Stdin = streams.stdin
+		cmd.Env = ProxyEnv(tool, cfg, os.Environ())
+		err = cmd.Run()
+	}
+
+	if cfg.AuditLog {
+		dir, _ := os.Getwd()
+		WriteAuditEntry(cfg, AuditEntry{
+			Time:       started,
+			Engine:     tool,
+			Args:       RedactArgs(translatedArgs),
+			Dir:        dir,
+			ExitCode:   exitCode(err),
+			DurationMs: time.Since(started).Milliseconds(),
+		})
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to run %s in WSL: %w", tool, err)
 	}
 
-	daemonName := engine + "d"
-	serviceName := engine
-	socketPath := "/var/run/docker.sock"
-	daemonArgs := ""
+	return nil
+}
+
+// daemonSpec says how an engine's daemon is found and started
+type daemonSpec struct {
+	engine  string // the engine owning the daemon (k3d and kubectl use another's)
+	daemon  string // process name
+	service string // init script name
+	socket  string
+	args    string
+}
 
+func engineDaemon(engine string) daemonSpec {
 	switch engine {
 	case "podman":
-		daemonName = "podman"
-		serviceName = "podman"
-		socketPath = "/run/podman/podman.sock"
-		daemonArgs = "system service" // Podman needs this to provide a Docker-compatible socket
+		// Podman needs 'system service' to provide a Docker-compatible socket
+		return daemonSpec{"podman", "podman", "podman", "/run/podman/podman.sock", "system service"}
 	case "k3s", "kubectl":
-		daemonName = "k3s"
-		serviceName = "k3s"
-		socketPath = "/run/k3s/containerd/containerd.sock"
-		daemonArgs = "server"
-		engine = "k3s"
+		return daemonSpec{"k3s", "k3s", "k3s", "/run/k3s/containerd/containerd.sock", "server"}
 	case "nerdctl":
-		daemonName = "containerd"
-		serviceName = "containerd"
-		socketPath = "/run/containerd/containerd.sock"
+		return daemonSpec{"nerdctl", "containerd", "containerd", "/run/containerd/containerd.sock", ""}
 	case "k3d":
 		// k3d depends on docker
-		daemonName = "dockerd"
-		serviceName = "docker"
-		socketPath = "/run/docker.sock"
-		engine = "docker"
+		return daemonSpec{"docker", "dockerd", "docker", "/run/docker.sock", ""}
+	}
+	return daemonSpec{engine, engine + "d", engine, "/var/run/docker.sock", ""}
+}
+
+// daemonUp probes whether the engine's daemon runs and has its socket, without starting
+// anything. Tests swap it out.
+var daemonUp = func(engine string) bool {
+	if engine == "podman" && !defaultUserIsRoot() {
+		return true // rootless podman has no daemon to be down
+	}
+	d := engineDaemon(engine)
+	probe := fmt.Sprintf("pgrep -x %s >/dev/null && test -S %s", d.daemon, d.socket)
+	return wslCommand("-d", DistroName, "-u", "root", "-e", "sh", "-c", probe).Run() == nil
+}
+
+// EnsureEngineRunning starts the engine's daemon if needed. Concurrent callers (even from
+// other ezship processes) wait for a single startup and then see its result.
+func EnsureEngineRunning(engine string) error {
+	return withLock(OperationLock, "starting "+engine, func() error {
+		return startEngine(engine)
+	})
+}
+
+// startEngine starts an engine's daemon; callers hold the operation lock. Tests swap it out.
+var startEngine = ensureEngineRunning
+
+func ensureEngineRunning(engine string) error {
+	// Pre-requisite: ensure distro exists
+	installed, err := IsDistroInstalled()
+	if err != nil || !installed {
+		if setupErr := setupDistro(SetupOptions{}); setupErr != nil {
+			return fmt.Errorf("distro not installed and setup failed: %w", setupErr)
+		}
 	}
 
+	// Rootless podman, as the provisioned user runs it, is daemonless. A rootful service
+	// would keep its containers apart from the user's, so it only serves root distros.
+	if engine == "podman" && !defaultUserIsRoot() {
+		return nil
+	}
+
+	d := engineDaemon(engine)
+	engine, daemonName, serviceName, socketPath, daemonArgs := d.engine, d.daemon, d.service, d.socket, d.args
+
 	// Check if daemon is running using pgrep
 	checkCmd := exec.Command("wsl", "-d", DistroName, "pgrep", "-x", daemonName)
 	if err := checkCmd.Run(); err == nil {
 		return nil // Already running
 	}
 
-	fmt.Printf("Starting %s daemon...\n", engine)
+	// Status goes to stderr so it never corrupts proxied stdout (e.g. 'docker save > img.tar')
+	fmt.Fprintf(os.Stderr, "Starting %s daemon...\n", engine)
 
 	// Startup sequence:
 	// 1. Try starting via 'service'
@@ -101,11 +275,11 @@ func EnsureEngineRunning(engine string) error {
 	}
 
 	// Wait for socket to be ready (up to 20 seconds)
-	fmt.Printf("Waiting for %s socket at %s...\n", engine, socketPath)
+	fmt.Fprintf(os.Stderr, "Waiting for %s socket at %s...\n", engine, socketPath)
 	for i := 0; i < 40; i++ {
 		checkSocket := exec.Command("wsl", "-d", DistroName, "ls", socketPath)
 		if err := checkSocket.Run(); err == nil {
-			fmt.Printf("%s daemon is ready.\n", engine)
+			fmt.Fprintf(os.Stderr, "%s daemon is ready.\n", engine)
 			return nil
 		}
 		time.Sleep(500 * time.Millisecond)
@@ -116,7 +290,7 @@ func EnsureEngineRunning(engine string) error {
 
 // IsDistroInstalled checks if the ezship distro is registered in WSL
 func IsDistroInstalled() (bool, error) {
-	cmd := exec.Command("wsl", "--list", "--quiet")
+	cmd := wslCommand("--list", "--quiet")
 	output, err := cmd.Output()
 	if err != nil {
 		// If wsl --list fails, we assume it's not installed or WSL is broken
@@ -126,44 +300,17 @@ func IsDistroInstalled() (bool, error) {
 	// Output is usually UTF-16LE on Windows
 	// A simple way to handle this is to remove null bytes and check the string
 	s := strings.ReplaceAll(string(output), "\x00", "")
-	return strings.Contains(s, DistroName), nil
+	// Match whole lines: "ezship" must not match a profile distro like "ezship-work"
+	for _, line := range strings.Split(s, "\n") {
+		if strings.EqualFold(strings.TrimSpace(line), DistroName) {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
-// CreateProxyBinary creates a copy of the current executable with a different name in the same directory
+// CreateProxyBinary creates a copy of the current executable with a different name in the same directory.
+// Stale copies from an older ezship are refreshed; foreign binaries (e.g. a real Docker CLI) are left alone.
 func CreateProxyBinary(alias string) error {
-	exePath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
-	}
-
-	installDir := filepath.Dir(exePath)
-	proxyPath := filepath.Join(installDir, alias+".exe")
-
-	// If it already exists, don't overwrite it (could be a custom user binary?)
-	// But in our case, we probably want to ensure it's OUR proxy.
-	// For now, let's just create it if it doesn't exist.
-	if _, err := os.Stat(proxyPath); err == nil {
-		return nil
-	}
-
-	// Use io.Copy to create a physical copy
-	source, err := os.Open(exePath)
-	if err != nil {
-		return err
-	}
-	defer source.Close()
-
-	destination, err := os.OpenFile(proxyPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
-	if err != nil {
-		return err
-	}
-	defer destination.Close()
-
-	_, err = io.Copy(destination, source)
-	if err != nil {
-		return fmt.Errorf("failed to create proxy binary %s: %w", alias, err)
-	}
-
-	fmt.Printf("Created global alias: %s\n", alias)
-	return nil
+	return CreateAlias(alias, false)
 }
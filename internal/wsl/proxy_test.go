@@ -0,0 +1,210 @@
+package wsl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestHelperProcess is the stub wsl.exe backend. By default it echoes stdin to stdout untouched;
+// "ezship-stub-print <text>" prints text instead (e.g. a 'wsl --list' or 'wsl --export' result),
+// and "ezship-stub-fail <text>" prints it to stderr and fails.
+func TestHelperProcess(t *testing.T) {
+	if len(os.Args) < 2 {
+		return
+	}
+	args := os.Args[len(os.Args)-1:]
+	if len(os.Args) >= 3 && strings.HasPrefix(os.Args[len(os.Args)-2], "ezship-stub-") {
+		args = os.Args[len(os.Args)-2:]
+	}
+	switch args[0] {
+	case "ezship-stub-backend":
+		io.Copy(os.Stdout, os.Stdin)
+	case "ezship-stub-print":
+		io.WriteString(os.Stdout, args[1])
+	case "ezship-stub-fail":
+		io.WriteString(os.Stderr, args[1])
+		os.Exit(1)
+	default:
+		return
+	}
+	os.Exit(0)
+}
+
+// stubCommand returns a stub backend command printing text
+func stubCommand(text string) *exec.Cmd {
+	return exec.Command(os.Args[0], "-test.run=^TestHelperProcess$", "--", "ezship-stub-print", text)
+}
+
+// stubResponse answers the stubbed wsl calls whose arguments contain match
+type stubResponse struct {
+	match  string
+	output string
+	reply  func(args []string) string // computes the output instead, when set
+}
+
+// wslRecorder keeps the arguments of every stubbed wsl call. Engines run concurrently in some
+// operations, so it is safe for concurrent use.
+type wslRecorder struct {
+	mu    sync.Mutex
+	calls [][]string
+}
+
+// Args returns the recorded calls
+func (r *wslRecorder) Args() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([][]string(nil), r.calls...)
+}
+
+// Calls returns the recorded calls with their arguments joined by spaces
+func (r *wslRecorder) Calls() []string {
+	var out []string
+	for _, args := range r.Args() {
+		out = append(out, strings.Join(args, " "))
+	}
+	return out
+}
+
+// Reset forgets the calls recorded so far
+func (r *wslRecorder) Reset() {
+	r.mu.Lock()
+	r.calls = nil
+	r.mu.Unlock()
+}
+
+// stubWSL gives the test its own APPDATA and replaces wsl.exe with a stub printing the output of
+// the first matching response (nothing if none matches). Every call is recorded.
+func stubWSL(t *testing.T, responses ...stubResponse) *wslRecorder {
+	t.Helper()
+	t.Setenv("APPDATA", t.TempDir())
+	rec := &wslRecorder{}
+	orig := wslCommand
+	t.Cleanup(func() { wslCommand = orig })
+	wslCommand = func(args ...string) *exec.Cmd {
+		rec.mu.Lock()
+		rec.calls = append(rec.calls, args)
+		rec.mu.Unlock()
+		cmd := strings.Join(args, " ")
+		for _, r := range responses {
+			if strings.Contains(cmd, r.match) {
+				if r.reply != nil {
+					return stubCommand(r.reply(args))
+				}
+				return stubCommand(r.output)
+			}
+		}
+		return stubCommand("")
+	}
+	return rec
+}
+
+func stubBackend(t *testing.T) {
+	t.Helper()
+	t.Setenv("APPDATA", t.TempDir())
+	orig := wslCommand
+	wslCommand = func(args ...string) *exec.Cmd {
+		// runProxy replaces the environment, so the stub is selected by argument instead
+		return exec.Command(os.Args[0], "-test.run=^TestHelperProcess$", "--", "ezship-stub-backend")
+	}
+	t.Cleanup(func() { wslCommand = orig })
+}
+
+// hashWriter counts and hashes everything written to it
+type hashWriter struct {
+	n int64
+	h io.Writer
+}
+
+func (w *hashWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return w.h.Write(p)
+}
+
+func TestProxyBinaryStream(t *testing.T) {
+	stubBackend(t)
+
+	size := int64(300 << 20)
+	if testing.Short() {
+		size = 8 << 20
+	}
+
+	// Random bytes include CR, LF, NUL and Ctrl-Z, which any text conversion would mangle
+	src := io.LimitReader(rand.New(rand.NewSource(42)), size)
+	inHash := sha256.New()
+	in := io.TeeReader(src, inHash)
+
+	outHash := sha256.New()
+	out := &hashWriter{h: outHash}
+
+	// "skopeo" depends on no engine, so no daemon checks run against the stub
+	if err := runProxy("skopeo", nil, proxyStreams{stdin: in, stdout: out, stderr: io.Discard}); err != nil {
+		t.Fatalf("runProxy failed: %v", err)
+	}
+
+	if out.n != size {
+		t.Fatalf("Expected %d bytes through the proxy, got %d", size, out.n)
+	}
+	if !bytes.Equal(inHash.Sum(nil), outHash.Sum(nil)) {
+		t.Error("Stream was altered by the proxy")
+	}
+}
+
+func TestProxyFileStream(t *testing.T) {
+	stubBackend(t)
+
+	dir := t.TempDir()
+	data := []byte("line1\r\nline2\n\x00\x1a\xff\xfe binary tail")
+	inPath := dir + "/in.tar"
+	outPath := dir + "/out.tar"
+	os.WriteFile(inPath, data, 0644)
+
+	in, _ := os.Open(inPath)
+	defer in.Close()
+	out, _ := os.Create(outPath)
+
+	// With *os.File streams the handles are passed to the backend directly
+	if err := runProxy("skopeo", nil, proxyStreams{stdin: in, stdout: out, stderr: io.Discard}); err != nil {
+		t.Fatalf("runProxy failed: %v", err)
+	}
+	out.Close()
+
+	got, _ := os.ReadFile(outPath)
+	if !bytes.Equal(got, data) {
+		t.Errorf("File stream altered: got %q; want %q", got, data)
+	}
+}
+
+func TestProxyReversePaths(t *testing.T) {
+	stubBackend(t)
+	if err := SaveConfig(Config{ReversePaths: true}); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	input := "Source: /mnt/c/Users/me/data\n"
+
+	var console bytes.Buffer
+	streams := proxyStreams{stdin: strings.NewReader(input), stdout: &console, stderr: io.Discard, outTerminal: true}
+	if err := runProxy("skopeo", nil, streams); err != nil {
+		t.Fatalf("runProxy failed: %v", err)
+	}
+	if console.String() != "Source: C:\\Users\\me\\data\n" {
+		t.Errorf("Expected rewritten output on a console, got %q", console.String())
+	}
+
+	// Redirected output is left untouched
+	var file bytes.Buffer
+	streams = proxyStreams{stdin: strings.NewReader(input), stdout: &file, stderr: io.Discard}
+	if err := runProxy("skopeo", nil, streams); err != nil {
+		t.Fatalf("runProxy failed: %v", err)
+	}
+	if file.String() != input {
+		t.Errorf("Expected raw output when not a console, got %q", file.String())
+	}
+}
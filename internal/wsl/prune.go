@@ -0,0 +1,434 @@
+package wsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PruneEngineNames lists the engines Prune can clean, in report order
+var PruneEngineNames = []string{"docker", "podman", "nerdctl", "k3s"}
+
+// PruneOptions selects what Prune removes
+type PruneOptions struct {
+	Engines []string // engines to prune (empty: every running engine)
+	Until   string   // only remove objects older than this duration, e.g. "72h" (prune_until in the config)
+	Labels  []string // only remove objects carrying these labels ("key" or "key=value")
+	Volumes bool     // also remove unused volumes (kept by default; prune_volumes in the config)
+	DryRun  bool     // only list what would be removed
+}
+
+// PruneResult reports what Prune did to one engine (or would do, for a dry run)
+type PruneResult struct {
+	Engine     string
+	Skipped    string   // why the engine was left alone, if it was
+	Candidates []string // dry run: the objects that would be removed
+	Reclaimed  int64    // bytes freed (dry run: estimate)
+	Err        error
+}
+
+// Summary describes the result in one line
+func (r PruneResult) Summary() string {
+	switch {
+	case r.Err != nil:
+		return fmt.Sprintf("%s: %v", r.Engine, r.Err)
+	case r.Skipped != "":
+		return fmt.Sprintf("%s: skipped (%s)", r.Engine, r.Skipped)
+	case r.Candidates != nil:
+		return fmt.Sprintf("%s: %d objects, about %s", r.Engine, len(r.Candidates), FormatSize(r.Reclaimed))
+	}
+	return fmt.Sprintf("%s: reclaimed %s", r.Engine, FormatSize(r.Reclaimed))
+}
+
+var (
+	reclaimedPattern = regexp.MustCompile(`Total reclaimed space:\s*(\S+)`)
+	imageIDPattern   = regexp.MustCompile(`^(sha256:)?[0-9a-f]{12,64}$`)
+)
+
+// resolvePruneOptions validates the options and fills in the config's prune policy
+func resolvePruneOptions(opts PruneOptions, cfg Config) (PruneOptions, error) {
+	if opts.Until == "" {
+		opts.Until = cfg.PruneUntil
+	}
+	if opts.Until != "" {
+		if d, err := time.ParseDuration(opts.Until); err != nil || d <= 0 {
+			return opts, fmt.Errorf("invalid until filter %q (use a duration such as 72h)", opts.Until)
+		}
+	}
+	opts.Volumes = opts.Volumes || cfg.PruneVolumes
+	for _, l := range opts.Labels {
+		if l == "" || strings.HasPrefix(l, "=") {
+			return opts, fmt.Errorf("invalid label filter %q (use key or key=value)", l)
+		}
+	}
+	for _, e := range opts.Engines {
+		if !containsString(PruneEngineNames, e) {
+			return opts, fmt.Errorf("cannot prune %q (available: %s)", e, strings.Join(PruneEngineNames, ", "))
+		}
+	}
+	return opts, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// filterArgs renders the until and label filters as --filter flags
+func (opts PruneOptions) filterArgs() []string {
+	var args []string
+	if opts.Until != "" {
+		args = append(args, "--filter", "until="+opts.Until)
+	}
+	for _, l := range opts.Labels {
+		args = append(args, "--filter", "label="+l)
+	}
+	return args
+}
+
+func (opts PruneOptions) hasFilters() bool {
+	return opts.Until != "" || len(opts.Labels) > 0
+}
+
+// pruneCommands returns the commands pruning an engine (without 'wsl -d ... -e')
+func pruneCommands(engine string, opts PruneOptions) [][]string {
+	switch engine {
+	case "k3s":
+		return [][]string{{"k3s", "crictl", "rmi", "--prune"}}
+	case "nerdctl":
+		args := []string{"nerdctl", "system", "prune", "-a", "-f"}
+		if opts.Volumes {
+			args = append(args, "--volumes")
+		}
+		return [][]string{args}
+	}
+	args := []string{engine, "system", "prune", "-a", "-f"}
+	if opts.Volumes && opts.Until == "" {
+		args = append(args, "--volumes")
+	}
+	cmds := [][]string{append(args, opts.filterArgs()...)}
+	if opts.Volumes && opts.Until != "" {
+		// Docker rejects the until filter together with --volumes, and volumes have no age to
+		// filter on anyway: prune them on their own with the label filters only
+		volumes := []string{engine, "volume", "prune", "-f"}
+		for _, l := range opts.Labels {
+			volumes = append(volumes, "--filter", "label="+l)
+		}
+		cmds = append(cmds, volumes)
+	}
+	return cmds
+}
+
+// Prune removes unused containers, images and (optionally) volumes from the selected engines.
+// Volumes are kept unless asked for, and a dry run only lists what would be removed.
+func Prune(opts PruneOptions) ([]PruneResult, error) {
+	var results []PruneResult
+	err := withLock(OperationLock, "pruning engines", func() error {
+		var err error
+		results, err = prune(opts)
+		return err
+	})
+	return results, err
+}
+
+func prune(opts PruneOptions) ([]PruneResult, error) {
+	opts, err := resolvePruneOptions(opts, LoadConfig())
+	if err != nil {
+		return nil, err
+	}
+	engines := opts.Engines
+	if len(engines) == 0 {
+		engines = PruneEngineNames
+	}
+
+	if !opts.DryRun {
+		if err := takeSnapshot("prune"); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]PruneResult, len(engines))
+	var wg sync.WaitGroup
+	for i, engine := range engines {
+		wg.Add(1)
+		go func(i int, engine string) {
+			defer wg.Done()
+			results[i] = pruneEngine(engine, opts)
+		}(i, engine)
+	}
+	wg.Wait()
+
+	var errs []string
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, r.Summary())
+		}
+	}
+	if len(errs) > 0 {
+		return results, fmt.Errorf("prune errors: %s", strings.Join(errs, "; "))
+	}
+	return results, nil
+}
+
+func pruneEngine(engine string, opts PruneOptions) PruneResult {
+	res := PruneResult{Engine: engine}
+	if !engineRunning(engine) {
+		res.Skipped = "not running"
+		return res
+	}
+	// crictl and nerdctl's prune have no filters: skip rather than remove more than asked for
+	if opts.hasFilters() {
+		switch engine {
+		case "k3s":
+			res.Skipped = "crictl rmi --prune does not support filters"
+			return res
+		case "nerdctl":
+			res.Skipped = "nerdctl system prune does not support filters"
+			return res
+		}
+	}
+
+	if opts.DryRun {
+		var err error
+		if engine == "k3s" {
+			res.Candidates, res.Reclaimed, err = listCrictlCandidates()
+		} else {
+			res.Candidates, res.Reclaimed, err = listPruneCandidates(engine, opts)
+		}
+		res.Err = err
+		if res.Candidates == nil && err == nil {
+			res.Candidates = []string{}
+		}
+		return res
+	}
+
+	// containerd-based engines do not report what they freed, so measure their store
+	store := containerdStore(engine)
+	var before int64
+	if store != "" {
+		before = storeSize(store)
+	}
+
+	for _, args := range pruneCommands(engine, opts) {
+		output, err := wslCommand(engineCommandArgs(engine, args...)...).CombinedOutput()
+		if err != nil {
+			res.Err = fmt.Errorf("%s (%w)", strings.TrimSpace(string(output)), err)
+			return res
+		}
+		if m := reclaimedPattern.FindStringSubmatch(string(output)); m != nil && store == "" {
+			n, _ := parseHumanSize(m[1])
+			res.Reclaimed += n
+		}
+	}
+
+	if store != "" {
+		if after := storeSize(store); after < before {
+			res.Reclaimed = before - after
+		}
+	}
+	return res
+}
+
+// containerdStore returns the on-disk store of a containerd-based engine ("" for others)
+func containerdStore(engine string) string {
+	for _, c := range containerdDirs {
+		if c.source == engine {
+			return c.dir
+		}
+	}
+	return ""
+}
+
+func storeSize(dir string) int64 {
+	output, _ := wslCommand("-d", DistroName, "-u", "root", "-e", "du", "-sk", dir).Output()
+	return parseDu(string(output))[dir]
+}
+
+// engineList runs a listing command of a docker-compatible engine and splits its tab-separated rows
+func engineList(engine string, args ...string) ([][]string, error) {
+	output, err := wslCommand(engineCommandArgs(engine, append([]string{engine}, args...)...)...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", engine, args[0], err)
+	}
+	var rows [][]string
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.TrimSpace(line) != "" {
+			rows = append(rows, strings.Split(line, "\t"))
+		}
+	}
+	return rows, nil
+}
+
+// olderThan reports whether an engine's CreatedAt timestamp is before the cutoff.
+// Unparsable timestamps count as old, so the listing errs on the side of showing too much.
+func olderThan(createdAt string, cutoff time.Time) bool {
+	if cutoff.IsZero() {
+		return true
+	}
+	// "2024-01-02 15:04:05 +0000 UTC"; podman adds fractional seconds, which Parse accepts
+	t, err := time.Parse("2006-01-02 15:04:05 -0700 MST", strings.TrimSpace(createdAt))
+	if err != nil {
+		return true
+	}
+	return t.Before(cutoff)
+}
+
+// normalizeImageRef adds the implicit :latest tag so container and image references compare equal
+func normalizeImageRef(ref string) string {
+	if i := strings.LastIndex(ref, ":"); i < 0 || i < strings.LastIndex(ref, "/") {
+		return ref + ":latest"
+	}
+	return ref
+}
+
+// listPruneCandidates lists what 'system prune -a' would remove from a docker-compatible engine,
+// with an estimate of the space it would free
+func listPruneCandidates(engine string, opts PruneOptions) ([]string, int64, error) {
+	var cutoff time.Time
+	if opts.Until != "" {
+		d, _ := time.ParseDuration(opts.Until)
+		cutoff = time.Now().Add(-d)
+	}
+	var labelArgs []string
+	for _, l := range opts.Labels {
+		labelArgs = append(labelArgs, "--filter", "label="+l)
+	}
+
+	const psFormat = "{{.ID}}\t{{.Image}}\t{{.Status}}\t{{.CreatedAt}}\t{{.Names}}"
+	all, err := engineList(engine, "ps", "-a", "--format", psFormat)
+	if err != nil {
+		return nil, 0, err
+	}
+	matching, err := engineList(engine, append([]string{"ps", "-a", "--format", psFormat}, labelArgs...)...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var candidates []string
+	removed := make(map[string]bool)
+	for _, c := range matching {
+		if len(c) < 5 || strings.HasPrefix(c[2], "Up") || !olderThan(c[3], cutoff) {
+			continue
+		}
+		removed[c[0]] = true
+		candidates = append(candidates, fmt.Sprintf("container %s (%s)", c[4], c[1]))
+	}
+
+	// Images stay while a surviving container uses them
+	inUse := make(map[string]bool)
+	for _, c := range all {
+		if len(c) >= 2 && !removed[c[0]] {
+			inUse[normalizeImageRef(c[1])] = true
+			inUse[c[1]] = true
+		}
+	}
+	images, err := engineList(engine, append([]string{"images", "--format", "{{.ID}}\t{{.Repository}}:{{.Tag}}\t{{.CreatedAt}}\t{{.Size}}"}, labelArgs...)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	var reclaim int64
+	for _, img := range images {
+		if len(img) < 4 || !olderThan(img[2], cutoff) {
+			continue
+		}
+		id, ref := img[0], img[1]
+		used := inUse[normalizeImageRef(ref)]
+		// Containers started from an image ID reference it by (a prefix of) that ID
+		for r := range inUse {
+			if imageIDPattern.MatchString(r) && strings.HasPrefix(strings.TrimPrefix(id, "sha256:"), strings.TrimPrefix(r, "sha256:")) {
+				used = true
+			}
+		}
+		if used {
+			continue
+		}
+		if ref == "<none>:<none>" {
+			ref = id
+		}
+		size, _ := parseHumanSize(img[3])
+		reclaim += size
+		candidates = append(candidates, fmt.Sprintf("image %s (%s)", ref, FormatSize(size)))
+	}
+
+	if opts.Volumes {
+		volumes, err := engineList(engine, append([]string{"volume", "ls", "--filter", "dangling=true", "--format", "{{.Name}}"}, labelArgs...)...)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, v := range volumes {
+			candidates = append(candidates, "volume "+v[0])
+		}
+	}
+	return candidates, reclaim, nil
+}
+
+// crictlImages is the part of 'crictl images -o json' we need
+type crictlImages struct {
+	Images []struct {
+		ID       string   `json:"id"`
+		RepoTags []string `json:"repoTags"`
+		Size     string   `json:"size"`
+	} `json:"images"`
+}
+
+// crictlContainers is the part of 'crictl ps -a -o json' we need
+type crictlContainers struct {
+	Containers []struct {
+		ImageRef string `json:"imageRef"`
+	} `json:"containers"`
+}
+
+// listCrictlCandidates lists the images 'crictl rmi --prune' would remove (those no container uses)
+func listCrictlCandidates() ([]string, int64, error) {
+	run := func(args ...string) ([]byte, error) {
+		output, err := wslCommand(engineCommandArgs("k3s", append([]string{"k3s", "crictl"}, args...)...)...).Output()
+		if err != nil {
+			return nil, fmt.Errorf("crictl %s failed: %w", args[0], err)
+		}
+		return output, nil
+	}
+	output, err := run("ps", "-a", "-o", "json")
+	if err != nil {
+		return nil, 0, err
+	}
+	var containers crictlContainers
+	if err := json.Unmarshal(output, &containers); err != nil {
+		return nil, 0, fmt.Errorf("unexpected crictl ps output: %w", err)
+	}
+	inUse := make(map[string]bool)
+	for _, c := range containers.Containers {
+		inUse[c.ImageRef] = true
+	}
+
+	output, err = run("images", "-o", "json")
+	if err != nil {
+		return nil, 0, err
+	}
+	var images crictlImages
+	if err := json.Unmarshal(output, &images); err != nil {
+		return nil, 0, fmt.Errorf("unexpected crictl images output: %w", err)
+	}
+	var candidates []string
+	var reclaim int64
+	for _, img := range images.Images {
+		if inUse[img.ID] {
+			continue
+		}
+		name := img.ID
+		if len(img.RepoTags) > 0 {
+			name = img.RepoTags[0]
+		}
+		size, _ := strconv.ParseInt(img.Size, 10, 64)
+		reclaim += size
+		candidates = append(candidates, fmt.Sprintf("image %s (%s)", name, FormatSize(size)))
+	}
+	return candidates, reclaim, nil
+}
@@ -0,0 +1,184 @@
+package wsl
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestResolvePruneOptions(t *testing.T) {
+	opts, err := resolvePruneOptions(PruneOptions{}, Config{PruneUntil: "72h", PruneVolumes: true})
+	if err != nil || opts.Until != "72h" || !opts.Volumes {
+		t.Errorf("Expected the config policy to apply, got %+v, %v", opts, err)
+	}
+	opts, _ = resolvePruneOptions(PruneOptions{Until: "24h"}, Config{PruneUntil: "72h"})
+	if opts.Until != "24h" {
+		t.Errorf("Expected the flag to override the config, got %s", opts.Until)
+	}
+
+	for _, bad := range []PruneOptions{
+		{Until: "3 days"},
+		{Until: "-1h"},
+		{Labels: []string{"=value"}},
+		{Engines: []string{"k3d"}},
+	} {
+		if _, err := resolvePruneOptions(bad, Config{}); err == nil {
+			t.Errorf("Expected error for %+v", bad)
+		}
+	}
+}
+
+func TestPruneCommand(t *testing.T) {
+	join := func(cmds [][]string) string {
+		var lines []string
+		for _, c := range cmds {
+			lines = append(lines, strings.Join(c, " "))
+		}
+		return strings.Join(lines, "\n")
+	}
+	if got := join(pruneCommands("docker", PruneOptions{})); got != "docker system prune -a -f" {
+		t.Errorf("Volumes must be kept by default, got %s", got)
+	}
+	got := join(pruneCommands("docker", PruneOptions{Volumes: true, Labels: []string{"env=dev"}}))
+	if got != "docker system prune -a -f --volumes --filter label=env=dev" {
+		t.Errorf("Unexpected docker command: %s", got)
+	}
+	// docker rejects until together with --volumes: the volumes get their own pass
+	got = join(pruneCommands("podman", PruneOptions{Volumes: true, Until: "72h", Labels: []string{"env=dev"}}))
+	if got != "podman system prune -a -f --filter until=72h --filter label=env=dev\npodman volume prune -f --filter label=env=dev" {
+		t.Errorf("Unexpected podman commands: %s", got)
+	}
+	if got := join(pruneCommands("k3s", PruneOptions{})); got != "k3s crictl rmi --prune" {
+		t.Errorf("Unexpected k3s command: %s", got)
+	}
+}
+
+// stubPruneBackend fakes the engines, returning the recorded wsl calls
+func stubPruneBackend(t *testing.T, running ...string) *wslRecorder {
+	t.Helper()
+	var storeReads int32
+	calls := stubWSL(t,
+		stubResponse{match: "system prune", output: "Deleted Images:\nuntagged: nginx:latest\n\nTotal reclaimed space: 1.5GB\n"},
+		stubResponse{match: "du -sk", reply: func([]string) string {
+			// the k3s store shrinks between the reads before and after the prune
+			if atomic.AddInt32(&storeReads, 1) == 1 {
+				return "3072\t/var/lib/rancher/k3s/agent/containerd\n"
+			}
+			return "1024\t/var/lib/rancher/k3s/agent/containerd\n"
+		}},
+		stubResponse{match: "ps -a --format", reply: func(args []string) string {
+			if strings.Contains(strings.Join(args, " "), "label=") {
+				return "c2\tredis\tExited (0) 2 days ago\t2020-01-01 10:00:00 +0000 UTC\tcache\n"
+			}
+			return "c1\tnginx\tUp 2 hours\t2020-01-01 10:00:00 +0000 UTC\tweb\n" +
+				"c2\tredis\tExited (0) 2 days ago\t2020-01-01 10:00:00 +0000 UTC\tcache\n" +
+				"c3\tpostgres:16\tExited (1) 1 minute ago\t2999-01-01 10:00:00 +0000 UTC\tdb\n"
+		}},
+		stubResponse{match: "images --format", output: "i1\tnginx:latest\t2020-01-01 10:00:00 +0000 UTC\t142MB\n" +
+			"i2\tredis:latest\t2020-01-01 10:00:00 +0000 UTC\t100MB\n" +
+			"i3\t<none>:<none>\t2020-01-01 10:00:00 +0000 UTC\t10MB\n"},
+		stubResponse{match: "volume ls", output: "pgdata-old\n"},
+		stubResponse{match: "crictl ps", output: `{"containers":[{"imageRef":"sha256:aaa"}]}`},
+		stubResponse{match: "crictl images", output: `{"images":[{"id":"sha256:aaa","repoTags":["docker.io/rancher/pause:3.6"],"size":"300000"},{"id":"sha256:bbb","repoTags":["docker.io/library/busybox:latest"],"size":"2000000"}]}`},
+	)
+	orig := engineRunning
+	t.Cleanup(func() { engineRunning = orig })
+	engineRunning = func(engine string) bool { return containsString(running, engine) }
+	return calls
+}
+
+func TestPruneReportsReclaimedSpace(t *testing.T) {
+	calls := stubPruneBackend(t, "docker", "k3s")
+
+	results, err := prune(PruneOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	byEngine := make(map[string]PruneResult)
+	for _, r := range results {
+		byEngine[r.Engine] = r
+	}
+	if r := byEngine["docker"]; r.Reclaimed != 1500000000 {
+		t.Errorf("Expected docker to report 1.5GB reclaimed, got %+v", r)
+	}
+	if r := byEngine["k3s"]; r.Reclaimed != 2048*1024 {
+		t.Errorf("Expected k3s reclaimed space from its store size, got %+v", r)
+	}
+	if r := byEngine["podman"]; r.Skipped == "" {
+		t.Errorf("Expected podman to be skipped when not running, got %+v", r)
+	}
+	for _, c := range calls.Calls() {
+		if strings.Contains(c, "--volumes") {
+			t.Errorf("Volumes must be kept by default: %s", c)
+		}
+	}
+}
+
+func TestPruneFiltersSkipUnsupportedEngines(t *testing.T) {
+	calls := stubPruneBackend(t, "docker", "nerdctl", "k3s")
+
+	results, err := prune(PruneOptions{Until: "72h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if (r.Engine == "k3s" || r.Engine == "nerdctl") && r.Skipped == "" {
+			t.Errorf("Expected %s to be skipped with filters", r.Engine)
+		}
+	}
+	for _, c := range calls.Calls() {
+		if strings.Contains(c, "crictl") || strings.Contains(c, "nerdctl") {
+			t.Errorf("Unexpected call with filters: %s", c)
+		}
+	}
+}
+
+func TestPruneDryRun(t *testing.T) {
+	calls := stubPruneBackend(t, "docker", "k3s")
+
+	results, err := prune(PruneOptions{Engines: []string{"docker"}, Until: "72h", Volumes: true, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected only docker, got %+v", results)
+	}
+	got := strings.Join(results[0].Candidates, "\n")
+	for _, want := range []string{"container cache (redis)", "image redis:latest", "image i3", "volume pgdata-old"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in the dry run, got:\n%s", want, got)
+		}
+	}
+	// nginx is used by a running container, postgres' container is newer than the until filter
+	if strings.Contains(got, "nginx") || strings.Contains(got, "db") {
+		t.Errorf("Dry run lists objects that would be kept:\n%s", got)
+	}
+	if results[0].Reclaimed != 110000000 {
+		t.Errorf("Expected a 110MB estimate, got %d", results[0].Reclaimed)
+	}
+	for _, c := range calls.Calls() {
+		if strings.Contains(c, "prune") {
+			t.Errorf("Dry run must not prune: %s", c)
+		}
+	}
+
+	results, err = prune(PruneOptions{Engines: []string{"k3s"}, DryRun: true})
+	if err != nil || len(results[0].Candidates) != 1 || !strings.Contains(results[0].Candidates[0], "busybox") {
+		t.Errorf("Expected the unused k3s image, got %+v, %v", results, err)
+	}
+}
+
+func TestNormalizeImageRef(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                   "nginx:latest",
+		"nginx:1.25":              "nginx:1.25",
+		"localhost:5000/app":      "localhost:5000/app:latest",
+		"localhost:5000/app:v1":   "localhost:5000/app:v1",
+		"docker.io/library/redis": "docker.io/library/redis:latest",
+	}
+	for in, want := range tests {
+		if got := normalizeImageRef(in); got != want {
+			t.Errorf("normalizeImageRef(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
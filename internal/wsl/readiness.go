@@ -0,0 +1,110 @@
+package wsl
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultReadyCacheTTL is how long a successful engine check is trusted by the proxy
+const DefaultReadyCacheTTL = 60 * time.Second
+
+// connectionErrors are fragments printed by the engines when their daemon is unreachable
+var connectionErrors = []string{
+	"cannot connect to the docker daemon",
+	"is the docker daemon running",
+	"cannot connect to podman",
+	"unable to connect to podman socket",
+	"cannot access containerd socket",
+	"failed to dial",
+	"connection refused",
+	"the connection to the server",
+}
+
+// readyTTL returns the configured readiness cache TTL (a negative value disables the cache)
+func readyTTL(cfg Config) time.Duration {
+	if cfg.ReadyCacheTTL == 0 {
+		return DefaultReadyCacheTTL
+	}
+	return time.Duration(cfg.ReadyCacheTTL) * time.Second
+}
+
+func readyMarkerPath(engine string) string {
+	return filepath.Join(GetStateDir(), "ready-"+DistroName+"-"+engine)
+}
+
+// IsEngineReadyCached reports whether the engine was verified as running within the TTL
+func IsEngineReadyCached(engine string, ttl time.Duration) bool {
+	if ttl <= 0 {
+		return false
+	}
+	info, err := os.Stat(readyMarkerPath(engine))
+	if err != nil {
+		return false
+	}
+	age := time.Since(info.ModTime())
+	return age >= 0 && age < ttl
+}
+
+// MarkEngineReady records that the engine was just verified as running
+func MarkEngineReady(engine string) {
+	path := readyMarkerPath(engine)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	// Write to a temp file and rename so concurrent proxies never see a partial marker
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(time.Now().Format(time.RFC3339)), 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+}
+
+// InvalidateEngineReady forgets the cached readiness of an engine (or all engines if empty)
+func InvalidateEngineReady(engine string) {
+	if engine != "" {
+		os.Remove(readyMarkerPath(engine))
+		return
+	}
+	matches, _ := filepath.Glob(filepath.Join(GetStateDir(), "ready-*"))
+	for _, m := range matches {
+		os.Remove(m)
+	}
+}
+
+// isConnectionError checks whether an engine's stderr output points to an unreachable daemon
+func isConnectionError(stderr string) bool {
+	s := strings.ToLower(stderr)
+	for _, frag := range connectionErrors {
+		if strings.Contains(s, frag) {
+			return true
+		}
+	}
+	return false
+}
+
+// tailWriter forwards writes and keeps the last max bytes for later inspection
+type tailWriter struct {
+	w   io.Writer
+	buf []byte
+	max int
+}
+
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = t.buf[len(t.buf)-t.max:]
+	}
+	return t.w.Write(p)
+}
+
+func (t *tailWriter) String() string {
+	return string(t.buf)
+}
@@ -0,0 +1,130 @@
+package wsl
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEngineReadyCache(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+
+	if IsEngineReadyCached("docker", time.Minute) {
+		t.Fatal("Expected no cached readiness before marking")
+	}
+
+	MarkEngineReady("docker")
+	if !IsEngineReadyCached("docker", time.Minute) {
+		t.Error("Expected docker to be cached as ready")
+	}
+	if IsEngineReadyCached("docker", 0) {
+		t.Error("Expected a zero TTL to disable the cache")
+	}
+
+	// Age the marker past the TTL
+	old := time.Now().Add(-2 * time.Minute)
+	os.Chtimes(readyMarkerPath("docker"), old, old)
+	if IsEngineReadyCached("docker", time.Minute) {
+		t.Error("Expected expired marker to be ignored")
+	}
+
+	MarkEngineReady("podman")
+	InvalidateEngineReady("")
+	if IsEngineReadyCached("podman", time.Minute) {
+		t.Error("Expected invalidation to clear all markers")
+	}
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   bool
+	}{
+		{"Cannot connect to the Docker daemon at unix:///var/run/docker.sock. Is the docker daemon running?", true},
+		{"The connection to the server 127.0.0.1:6443 was refused - did you specify the right host or port?", true},
+		{"Error: unable to connect to Podman socket", true},
+		{"Error response from daemon: No such container: web", false},
+	}
+
+	for _, tt := range tests {
+		if got := isConnectionError(tt.stderr); got != tt.want {
+			t.Errorf("isConnectionError(%q) = %v; want %v", tt.stderr, got, tt.want)
+		}
+	}
+}
+
+func TestTailWriter(t *testing.T) {
+	var out bytes.Buffer
+	tw := &tailWriter{w: &out, max: 4}
+	tw.Write([]byte("hello "))
+	tw.Write([]byte("world"))
+
+	if out.String() != "hello world" {
+		t.Errorf("Expected all data forwarded, got %q", out.String())
+	}
+	if tw.String() != "orld" {
+		t.Errorf("Expected tail %q, got %q", "orld", tw.String())
+	}
+}
+
+func TestProxyStaleCacheWithPipedInput(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	runs := 0
+	orig := wslCommand
+	t.Cleanup(func() { wslCommand = orig })
+	wslCommand = func(args ...string) *exec.Cmd {
+		runs++
+		return exec.Command(os.Args[0], "-test.run=^TestHelperProcess$", "--", "ezship-stub-fail",
+			"Cannot connect to the Docker daemon at unix:///var/run/docker.sock.")
+	}
+	MarkEngineReady("docker")
+
+	// 'docker load < img.tar': the first attempt reads the input, so it is never re-run
+	var stderr bytes.Buffer
+	err := runProxy("docker", []string{"load"}, proxyStreams{stdin: strings.NewReader("image"), stdout: io.Discard, stderr: &stderr})
+	if err == nil || runs != 1 {
+		t.Errorf("Expected one failed attempt, got %d runs, %v", runs, err)
+	}
+	if !strings.Contains(stderr.String(), "Cannot connect") {
+		t.Errorf("Expected the engine's stderr to pass through, got %q", stderr.String())
+	}
+	if IsEngineReadyCached("docker", time.Minute) {
+		t.Error("Expected the failure to drop the cached readiness")
+	}
+}
+
+func TestProxyStaleCacheRetry(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	runs, started := 0, 0
+	orig, origUp, origStart := wslCommand, daemonUp, startEngine
+	t.Cleanup(func() { wslCommand, daemonUp, startEngine = orig, origUp, origStart })
+	daemonUp = func(string) bool { return started > 0 }
+	startEngine = func(string) error { started++; return nil }
+	wslCommand = func(args ...string) *exec.Cmd {
+		if runs++; runs == 1 {
+			return exec.Command(os.Args[0], "-test.run=^TestHelperProcess$", "--", "ezship-stub-fail", "daemon gone")
+		}
+		return stubCommand("ok")
+	}
+	MarkEngineReady("docker")
+
+	// An interactive 'docker ps': stderr is a console and is not read, the probe finds the daemon down
+	var stdout bytes.Buffer
+	streams := proxyStreams{stdin: strings.NewReader(""), stdout: &stdout, stderr: io.Discard, inTerminal: true, errTerminal: true}
+	if err := runProxy("docker", []string{"ps"}, streams); err != nil {
+		t.Fatalf("Expected the retry to succeed, got %v", err)
+	}
+	if runs != 2 || started != 1 || stdout.String() != "ok" {
+		t.Errorf("Expected one restart and one retry, got %d runs, %d starts, %q", runs, started, stdout.String())
+	}
+
+	// A failure with the daemon up is the command's own: no retry
+	runs = 0
+	if err := runProxy("docker", []string{"ps"}, streams); err == nil || runs != 1 {
+		t.Errorf("Expected a single failed run, got %d runs, %v", runs, err)
+	}
+}
@@ -0,0 +1,202 @@
+package wsl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// volumeEngines are the engines whose named volumes --keep-volumes carries over
+var volumeEngines = []string{"docker", "podman", "nerdctl"}
+
+// ResetOptions controls what Reset rebuilds after deleting the distro
+type ResetOptions struct {
+	Rebuild     bool // set the distro up again and reinstall the engines that were installed
+	KeepVolumes bool // export named volumes first and restore them after the rebuild
+}
+
+// ResetResult reports what Reset carried over
+type ResetResult struct {
+	Engines   []string // engines reinstalled
+	Volumes   []string // "<engine>/<volume>" restored
+	VolumeDir string   // where the volume exports are kept if restoring failed
+}
+
+// Reset deletes the active distro and, with Rebuild, sets it up again with the same engines
+func Reset(opts ResetOptions) (*ResetResult, error) {
+	var res *ResetResult
+	err := withLock(OperationLock, "resetting distro", func() error {
+		var err error
+		res, err = reset(opts)
+		return err
+	})
+	return res, err
+}
+
+func reset(opts ResetOptions) (*ResetResult, error) {
+	if opts.KeepVolumes && !opts.Rebuild {
+		return nil, errors.New("keeping volumes needs a rebuild to restore them into")
+	}
+	if installed, _ := IsDistroInstalled(); !installed {
+		return nil, fmt.Errorf("distro %s is not installed", DistroName)
+	}
+
+	res := &ResetResult{VolumeDir: filepath.Join(GetDataDir(), "volumes", DistroName)}
+	var engines []string
+	if opts.Rebuild {
+		engines = installedEngines()
+	}
+
+	var exported map[string][]string
+	if opts.KeepVolumes {
+		var err error
+		if exported, err = exportVolumes(engines, res.VolumeDir); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ResetDistro(); err != nil {
+		return nil, err
+	}
+	if !opts.Rebuild {
+		return res, nil
+	}
+
+	if err := setupDistro(SetupOptions{}); err != nil {
+		return res, fmt.Errorf("rebuild failed: %w", err)
+	}
+	for _, engine := range engines {
+		fmt.Printf("Reinstalling %s...\n", engine)
+		if err := installEngine(engine); err != nil {
+			return res, fmt.Errorf("reinstalling %s failed: %w", engine, err)
+		}
+		res.Engines = append(res.Engines, engine)
+	}
+
+	if len(exported) == 0 {
+		return res, nil
+	}
+	for _, engine := range volumeEngines {
+		for _, volume := range exported[engine] {
+			if err := importVolume(engine, volume, res.VolumeDir); err != nil {
+				return res, fmt.Errorf("restoring volume %s failed (exports kept in %s): %w", volume, res.VolumeDir, err)
+			}
+			res.Volumes = append(res.Volumes, engine+"/"+volume)
+		}
+	}
+	os.RemoveAll(res.VolumeDir)
+	return res, nil
+}
+
+// volumeArchive is where a volume's export is stored during a reset
+func volumeArchive(dir, engine, volume string) string {
+	return filepath.Join(dir, engine+"-"+volume+".tar")
+}
+
+// engineOutput runs an engine command inside the distro and returns its trimmed output
+func engineOutput(engine string, args ...string) (string, error) {
+	output, err := wslCommand(engineCommandArgs(engine, append([]string{engine}, args...)...)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s %s failed: %w", engine, strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// volumeTarCommand returns the wsl.exe arguments streaming a volume's content as a tar (or
+// unpacking one into it, with extract). Rootless podman volumes go through podman itself,
+// since their files belong to the user namespace; other stores are root-owned.
+func volumeTarCommand(engine, volume string, extract bool) ([]string, error) {
+	if engine == "podman" {
+		if extract {
+			return engineCommandArgs(engine, "podman", "volume", "import", volume, "-"), nil
+		}
+		return engineCommandArgs(engine, "podman", "volume", "export", volume), nil
+	}
+	mountpoint, err := engineOutput(engine, "volume", "inspect", "-f", "{{.Mountpoint}}", volume)
+	if err != nil {
+		return nil, err
+	}
+	if extract {
+		return []string{"-d", DistroName, "-u", "root", "-e", "tar", "-C", mountpoint, "-xf", "-"}, nil
+	}
+	return []string{"-d", DistroName, "-u", "root", "-e", "tar", "-C", mountpoint, "-cf", "-", "."}, nil
+}
+
+// exportVolumes saves the named volumes of the engines as tarballs in dir, by engine
+func exportVolumes(engines []string, dir string) (map[string][]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create volume export directory: %w", err)
+	}
+	exported := make(map[string][]string)
+	for _, engine := range engines {
+		if !containsString(volumeEngines, engine) {
+			continue
+		}
+		if err := startEngine(engine); err != nil {
+			return nil, fmt.Errorf("cannot export %s volumes: %w", engine, err)
+		}
+		list, err := engineOutput(engine, "volume", "ls", "-q")
+		if err != nil {
+			return nil, err
+		}
+		for _, volume := range strings.Fields(list) {
+			fmt.Printf("Exporting %s volume %s...\n", engine, volume)
+			if err := exportVolume(engine, volume, dir); err != nil {
+				return nil, err
+			}
+			exported[engine] = append(exported[engine], volume)
+		}
+	}
+	return exported, nil
+}
+
+func exportVolume(engine, volume, dir string) error {
+	args, err := volumeTarCommand(engine, volume, false)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(volumeArchive(dir, engine, volume))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var stderr strings.Builder
+	cmd := wslCommand(args...)
+	cmd.Stdout = f
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to export volume %s: %s (%w)", volume, strings.TrimSpace(stderr.String()), err)
+	}
+	return nil
+}
+
+// importVolume recreates a volume in the rebuilt engine and unpacks its export into it
+func importVolume(engine, volume, dir string) error {
+	if err := startEngine(engine); err != nil {
+		return err
+	}
+	if _, err := engineOutput(engine, "volume", "create", volume); err != nil {
+		return err
+	}
+	args, err := volumeTarCommand(engine, volume, true)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(volumeArchive(dir, engine, volume))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var stderr strings.Builder
+	cmd := wslCommand(args...)
+	cmd.Stdin = f
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s (%w)", strings.TrimSpace(stderr.String()), err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package wsl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stubVolumeBackend fakes the engine's volume commands and tar inside the distro
+func stubVolumeBackend(t *testing.T) *wslRecorder {
+	t.Helper()
+	last := func(args []string) string { return args[len(args)-1] }
+	calls := stubWSL(t, append(distroResponses("ezship\r\n"),
+		stubResponse{match: "volume ls -q", output: "pgdata\ncache\n"},
+		stubResponse{match: "volume inspect", reply: func(args []string) string { return "/var/lib/docker/volumes/" + last(args) + "/_data\n" }},
+		stubResponse{match: "tar -C", reply: func(args []string) string { return "archive of " + args[7] }},
+		stubResponse{match: "podman volume export", reply: func(args []string) string { return "podman archive of " + last(args) }},
+	)...)
+	orig := startEngine
+	startEngine = func(string) error { return nil }
+	t.Cleanup(func() { startEngine = orig })
+	return calls
+}
+
+func TestExportImportVolumes(t *testing.T) {
+	run := stubVolumeBackend(t)
+	dir := t.TempDir()
+
+	exported, err := exportVolumes([]string{"docker", "k3d"}, dir)
+	if err != nil {
+		t.Fatalf("exportVolumes failed: %v", err)
+	}
+	if strings.Join(exported["docker"], ",") != "pgdata,cache" || len(exported) != 1 {
+		t.Fatalf("Expected the docker volumes only, got %v", exported)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "docker-pgdata.tar"))
+	if err != nil || string(data) != "archive of /var/lib/docker/volumes/pgdata/_data" {
+		t.Errorf("Unexpected export %q, %v", data, err)
+	}
+
+	run.Reset()
+	if err := importVolume("docker", "pgdata", dir); err != nil {
+		t.Fatalf("importVolume failed: %v", err)
+	}
+	got := strings.Join(run.Calls(), "\n")
+	if !strings.Contains(got, "docker volume create pgdata") || !strings.Contains(got, "-u root -e tar -C /var/lib/docker/volumes/pgdata/_data -xf -") {
+		t.Errorf("Unexpected import commands:\n%s", got)
+	}
+}
+
+func TestExportPodmanVolumes(t *testing.T) {
+	run := stubVolumeBackend(t)
+	dir := t.TempDir()
+
+	if _, err := exportVolumes([]string{"podman"}, dir); err != nil {
+		t.Fatalf("exportVolumes failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "podman-cache.tar"))
+	if string(data) != "podman archive of cache" {
+		t.Errorf("Unexpected export %q", data)
+	}
+	// rootless podman volumes belong to the default user, not root
+	for _, c := range run.Calls() {
+		if strings.Contains(c, "-u root") {
+			t.Errorf("Podman volumes must be exported as the default user: %s", c)
+		}
+	}
+}
+
+func TestResetOptions(t *testing.T) {
+	calls := stubDistroBackend(t, "ezship\r\n")
+	if _, err := Reset(ResetOptions{KeepVolumes: true}); err == nil {
+		t.Error("Expected error when keeping volumes without a rebuild")
+	}
+
+	if _, err := Reset(ResetOptions{}); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	args := calls.Args()
+	last := args[len(args)-1]
+	if strings.Join(last, " ") != "--unregister ezship" {
+		t.Errorf("Expected the distro to be unregistered, last call %v", last)
+	}
+
+	stubDistroBackend(t, "Ubuntu\r\n")
+	if _, err := Reset(ResetOptions{}); err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Errorf("Expected not installed error, got %v", err)
+	}
+}
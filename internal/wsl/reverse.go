@@ -0,0 +1,104 @@
+package wsl
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// maxPendingOutput caps how much output the path rewriter holds back while waiting for a delimiter
+const maxPendingOutput = 64 * 1024
+
+// wslPathPattern matches /mnt/<drive> paths that start at a word boundary and end at whitespace
+// or punctuation, e.g. "/mnt/c/Users/me" but not "/mnt/cdrom" or "/data/mnt/c".
+var wslPathPattern = regexp.MustCompile(`(^|[\s"'=(\[{<,;])/mnt/([a-zA-Z])((?:/[^\s"'<>|,:;()\[\]{}]*)?)($|[\s"'<>|,:;()\[\]{}])`)
+
+// UntranslatePath converts WSL drive paths in s back to Windows paths (/mnt/c/Users -> C:\Users).
+// It is the inverse of TranslatePath.
+func UntranslatePath(s string) string {
+	// Matches may share their delimiters, so repeat until nothing changes
+	for {
+		out := wslPathPattern.ReplaceAllStringFunc(s, func(m string) string {
+			sub := wslPathPattern.FindStringSubmatch(m)
+			rest := strings.ReplaceAll(sub[3], "/", "\\")
+			if rest == "" {
+				rest = "\\"
+			}
+			return sub[1] + strings.ToUpper(sub[2]) + ":" + rest + sub[4]
+		})
+		if out == s {
+			return out
+		}
+		s = out
+	}
+}
+
+// textRewriter is a streaming filter that applies a text transform to engine output.
+// Output is only transformed up to the last cut character, so a match split across two writes
+// is still recognised. As soon as binary data (a NUL byte) shows up it becomes a plain pass-through.
+type textRewriter struct {
+	w         io.Writer
+	transform func(string) string
+	cutset    string
+	pending   []byte
+	binary    bool
+}
+
+// newPathRewriter returns a filter applying UntranslatePath, cutting at any whitespace
+func newPathRewriter(w io.Writer) *textRewriter {
+	return &textRewriter{w: w, transform: UntranslatePath, cutset: " \t\r\n"}
+}
+
+// newLineRewriter returns a filter applying transform to whole lines
+func newLineRewriter(w io.Writer, transform func(string) string) *textRewriter {
+	return &textRewriter{w: w, transform: transform, cutset: "\r\n"}
+}
+
+func (r *textRewriter) Write(p []byte) (int, error) {
+	if r.binary {
+		return r.w.Write(p)
+	}
+	if bytes.IndexByte(p, 0) >= 0 {
+		r.binary = true
+		if err := r.flushRaw(); err != nil {
+			return 0, err
+		}
+		return r.w.Write(p)
+	}
+
+	r.pending = append(r.pending, p...)
+	cut := bytes.LastIndexAny(r.pending, r.cutset) + 1
+	if cut == 0 && len(r.pending) < maxPendingOutput {
+		return len(p), nil
+	}
+	if cut == 0 {
+		cut = len(r.pending)
+	}
+
+	out := r.transform(string(r.pending[:cut]))
+	r.pending = append(r.pending[:0], r.pending[cut:]...)
+	if _, err := io.WriteString(r.w, out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (r *textRewriter) flushRaw() error {
+	if len(r.pending) == 0 {
+		return nil
+	}
+	_, err := r.w.Write(r.pending)
+	r.pending = nil
+	return err
+}
+
+// Flush writes out anything still held back
+func (r *textRewriter) Flush() error {
+	if r.binary || len(r.pending) == 0 {
+		return r.flushRaw()
+	}
+	_, err := io.WriteString(r.w, r.transform(string(r.pending)))
+	r.pending = nil
+	return err
+}
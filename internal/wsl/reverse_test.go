@@ -0,0 +1,71 @@
+package wsl
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestUntranslatePath(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"/mnt/c/Users", "C:\\Users"},
+		{"/mnt/d/Projetos/ezship", "D:\\Projetos\\ezship"},
+		{"/mnt/c/", "C:\\"},
+		{"/mnt/c", "C:\\"},
+		{`"Source": "/mnt/c/data",`, `"Source": "C:\data",`},
+		{"-v /mnt/c/a:/data", "-v C:\\a:/data"},
+		{"/mnt/c/a /mnt/d/b", "C:\\a D:\\b"},
+		{"/mnt/cdrom/x", "/mnt/cdrom/x"},
+		{"/data/mnt/c/x", "/data/mnt/c/x"},
+		{"random string", "random string"},
+	}
+
+	for _, tt := range tests {
+		result := UntranslatePath(tt.input)
+		if result != tt.expected {
+			t.Errorf("UntranslatePath(%s) = %s; want %s", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestUntranslatePathRoundTrip(t *testing.T) {
+	for _, p := range []string{"C:\\Users\\me\\project", "D:\\data"} {
+		if got := UntranslatePath(TranslatePath(p)); got != p {
+			t.Errorf("round trip of %s gave %s", p, got)
+		}
+	}
+}
+
+func TestPathRewriterChunkBoundaries(t *testing.T) {
+	input := "error: open /mnt/c/Users/me/app.yaml: no such file\nmount /mnt/d/data ok\n"
+	want := "error: open C:\\Users\\me\\app.yaml: no such file\nmount D:\\data ok\n"
+
+	// Feed the text in every possible pair of chunks
+	for split := 0; split <= len(input); split++ {
+		var out bytes.Buffer
+		r := newPathRewriter(&out)
+		r.Write([]byte(input[:split]))
+		r.Write([]byte(input[split:]))
+		r.Flush()
+		if out.String() != want {
+			t.Fatalf("split at %d: got %q; want %q", split, out.String(), want)
+		}
+	}
+}
+
+func TestPathRewriterBinaryPassthrough(t *testing.T) {
+	var out bytes.Buffer
+	r := newPathRewriter(&out)
+	r.Write([]byte("/mnt/c/x "))
+	data := []byte("\x00/mnt/c/keep\x00")
+	r.Write(data)
+	r.Write([]byte(" /mnt/c/also-kept"))
+	r.Flush()
+
+	want := "C:\\x \x00/mnt/c/keep\x00 /mnt/c/also-kept"
+	if out.String() != want {
+		t.Errorf("got %q; want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,447 @@
+package wsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tasks a maintenance job can run
+const (
+	TaskPrune  = "prune"
+	TaskVacuum = "vacuum"
+)
+
+const (
+	// MaintenanceLock is held while scheduled jobs run, so only one agent works at a time
+	MaintenanceLock = "maintenance"
+
+	defaultJobTime = "03:00"
+)
+
+// MaintenanceJob is a scheduled task from the "maintenance" list in the config
+type MaintenanceJob struct {
+	Name      string   `json:"name,omitempty"`       // identifies the job in the state file (default "<task>-<every>")
+	Task      string   `json:"task"`                 // prune or vacuum
+	Every     string   `json:"every,omitempty"`      // a weekday ("monday"), "daily" (default) or an interval ("12h", "7d")
+	At        string   `json:"at,omitempty"`         // time of day for weekday and daily schedules (default 03:00)
+	OlderThan string   `json:"older_than,omitempty"` // prune: only remove objects older than this ("7d")
+	Engines   []string `json:"engines,omitempty"`    // prune: engines to clean (default every running one)
+	VHDXOver  string   `json:"vhdx_over,omitempty"`  // vacuum: only when the disk file is larger than this ("40GB")
+}
+
+// ID returns the key of the job in the state file
+func (j MaintenanceJob) ID() string {
+	if j.Name != "" {
+		return j.Name
+	}
+	return j.Task + "-" + j.every()
+}
+
+// Disruptive reports whether the job stops the distro (vacuum), and with it every running container
+func (j MaintenanceJob) Disruptive() bool {
+	return j.Task == TaskVacuum
+}
+
+func (j MaintenanceJob) every() string {
+	if j.Every == "" {
+		return "daily"
+	}
+	return strings.ToLower(j.Every)
+}
+
+// schedule is a parsed Every/At pair: either a fixed interval or a time of day on some weekdays
+type schedule struct {
+	interval     time.Duration
+	weekday      time.Weekday
+	daily        bool
+	hour, minute int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// parseInterval parses a Go duration, also accepting whole days ("7d")
+func parseInterval(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid interval %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid interval %q", s)
+	}
+	return d, nil
+}
+
+func (j MaintenanceJob) schedule() (schedule, error) {
+	var s schedule
+	every := j.every()
+	if wd, ok := weekdays[every]; ok {
+		s.weekday = wd
+	} else if every == "daily" {
+		s.daily = true
+	} else {
+		d, err := parseInterval(every)
+		if err != nil {
+			return s, fmt.Errorf("job %s: every must be a weekday, daily or an interval: %w", j.ID(), err)
+		}
+		s.interval = d
+		return s, nil
+	}
+
+	at := j.At
+	if at == "" {
+		at = defaultJobTime
+	}
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return s, fmt.Errorf("job %s: invalid time %q (use HH:MM)", j.ID(), at)
+	}
+	s.hour, s.minute = t.Hour(), t.Minute()
+	return s, nil
+}
+
+// latest returns the most recent scheduled time at or before now (calendar schedules only)
+func (s schedule) latest(now time.Time) time.Time {
+	t := time.Date(now.Year(), now.Month(), now.Day(), s.hour, s.minute, 0, 0, now.Location())
+	if t.After(now) {
+		t = t.AddDate(0, 0, -1)
+	}
+	if !s.daily {
+		for t.Weekday() != s.weekday {
+			t = t.AddDate(0, 0, -1)
+		}
+	}
+	return t
+}
+
+// due reports whether a job last run at last (zero if never) should run at now
+func (s schedule) due(last, now time.Time) bool {
+	if last.IsZero() {
+		return true
+	}
+	if s.interval > 0 {
+		return !now.Before(last.Add(s.interval))
+	}
+	return last.Before(s.latest(now))
+}
+
+// next returns when a job last run at last runs again
+func (s schedule) next(last, now time.Time) time.Time {
+	if s.due(last, now) {
+		return now
+	}
+	if s.interval > 0 {
+		return last.Add(s.interval)
+	}
+	if s.daily {
+		return s.latest(now).AddDate(0, 0, 1)
+	}
+	return s.latest(now).AddDate(0, 0, 7)
+}
+
+// Validate checks the job's task, schedule and options
+func (j MaintenanceJob) Validate() error {
+	switch j.Task {
+	case TaskPrune:
+		if j.OlderThan != "" {
+			if _, err := parseInterval(j.OlderThan); err != nil {
+				return fmt.Errorf("job %s: older_than: %w", j.ID(), err)
+			}
+		}
+		for _, e := range j.Engines {
+			if !containsString(PruneEngineNames, e) {
+				return fmt.Errorf("job %s: cannot prune %q", j.ID(), e)
+			}
+		}
+	case TaskVacuum:
+		if j.VHDXOver != "" {
+			if _, err := parseHumanSize(j.VHDXOver); err != nil {
+				return fmt.Errorf("job %s: vhdx_over: %w", j.ID(), err)
+			}
+		}
+	default:
+		return fmt.Errorf("job %s: unknown task %q (use %s or %s)", j.ID(), j.Task, TaskPrune, TaskVacuum)
+	}
+	_, err := j.schedule()
+	return err
+}
+
+// JobRun records the last run of a maintenance job
+type JobRun struct {
+	Job    string    `json:"job"`
+	Time   time.Time `json:"time"`
+	Result string    `json:"result,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// MaintenanceState is the last-run state file of a distro's maintenance jobs
+type MaintenanceState struct {
+	Jobs map[string]JobRun    `json:"jobs"`
+	Seen map[string]time.Time `json:"seen,omitempty"` // when jobs that never ran were first scheduled
+}
+
+// lastRun returns when a job last ran, or when it was first seen if it never ran
+func (s MaintenanceState) lastRun(id string) time.Time {
+	if run, ok := s.Jobs[id]; ok {
+		return run.Time
+	}
+	return s.Seen[id]
+}
+
+func maintenanceStatePath() string {
+	return filepath.Join(GetStateDir(), "maintenance-"+DistroName+".json")
+}
+
+// LoadMaintenanceState reads the last runs of the active distro's maintenance jobs
+func LoadMaintenanceState() MaintenanceState {
+	state := MaintenanceState{Jobs: make(map[string]JobRun)}
+	if data, err := os.ReadFile(maintenanceStatePath()); err == nil {
+		json.Unmarshal(data, &state)
+		if state.Jobs == nil {
+			state.Jobs = make(map[string]JobRun)
+		}
+	}
+	return state
+}
+
+func (s MaintenanceState) save() error {
+	path := maintenanceStatePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Scheduler runs the configured maintenance jobs when they are due
+type Scheduler struct {
+	Jobs          []MaintenanceJob
+	Now           func() time.Time                     // the clock; tests use a fake one
+	Run           func(MaintenanceJob) (string, error) // runs a job and describes the result
+	NonDisruptive bool                                 // leave out jobs that stop the distro
+}
+
+// NewScheduler returns a scheduler for the config's jobs on the real clock
+func NewScheduler(cfg Config) *Scheduler {
+	return &Scheduler{Jobs: cfg.Maintenance, Now: time.Now, Run: runMaintenanceJob}
+}
+
+// seed records the jobs that were never scheduled as seen now, so a new job waits for its
+// first slot instead of running at once. It reports whether any job was added.
+func (s *Scheduler) seed(state *MaintenanceState) bool {
+	added := false
+	for _, j := range s.Jobs {
+		if j.Validate() != nil || !state.lastRun(j.ID()).IsZero() {
+			continue
+		}
+		if state.Seen == nil {
+			state.Seen = make(map[string]time.Time)
+		}
+		state.Seen[j.ID()] = s.Now()
+		added = true
+	}
+	return added
+}
+
+// Due returns the jobs that should run now. Invalid jobs never run, and neither do jobs
+// that were never seeded.
+func (s *Scheduler) Due(state MaintenanceState) []MaintenanceJob {
+	now := s.Now()
+	var due []MaintenanceJob
+	for _, j := range s.Jobs {
+		if j.Validate() != nil || (s.NonDisruptive && j.Disruptive()) {
+			continue
+		}
+		last := state.lastRun(j.ID())
+		if last.IsZero() {
+			continue
+		}
+		sched, _ := j.schedule()
+		if sched.due(last, now) {
+			due = append(due, j)
+		}
+	}
+	return due
+}
+
+// NextRun returns when the job runs next
+func (s *Scheduler) NextRun(j MaintenanceJob, state MaintenanceState) (time.Time, error) {
+	sched, err := j.schedule()
+	if err != nil {
+		return time.Time{}, err
+	}
+	last := state.lastRun(j.ID())
+	if last.IsZero() {
+		// Not seeded yet: it will be once the agent first looks at it
+		last = s.Now()
+	}
+	return sched.next(last, s.Now()), nil
+}
+
+// RunDue runs the due jobs one after another, recording each in the state file
+func (s *Scheduler) RunDue() ([]JobRun, error) {
+	state := LoadMaintenanceState()
+	if s.seed(&state) {
+		if err := state.save(); err != nil {
+			return nil, fmt.Errorf("failed to save maintenance state: %w", err)
+		}
+	}
+	var runs []JobRun
+	for _, j := range s.Due(state) {
+		run := JobRun{Job: j.ID(), Time: s.Now()}
+		result, err := s.Run(j)
+		run.Result = result
+		if err != nil {
+			run.Error = err.Error()
+		}
+		// A failed job waits for its next slot too, rather than retrying on every check
+		state.Jobs[run.Job] = run
+		if err := state.save(); err != nil {
+			return runs, fmt.Errorf("failed to save maintenance state: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// runMaintenanceJob runs a job against the active distro
+func runMaintenanceJob(j MaintenanceJob) (string, error) {
+	switch j.Task {
+	case TaskPrune:
+		opts := PruneOptions{Engines: j.Engines}
+		if j.OlderThan != "" {
+			d, _ := parseInterval(j.OlderThan)
+			opts.Until = d.String()
+		}
+		results, err := Prune(opts)
+		var summary []string
+		for _, r := range results {
+			summary = append(summary, r.Summary())
+		}
+		return strings.Join(summary, ", "), err
+
+	case TaskVacuum:
+		if j.VHDXOver != "" {
+			limit, _ := parseHumanSize(j.VHDXOver)
+			stat, err := os.Stat(filepath.Join(GetInstallDir(), "ext4.vhdx"))
+			if err != nil {
+				return "", err
+			}
+			if stat.Size() <= limit {
+				return fmt.Sprintf("skipped, disk file is %s (limit %s)", FormatSize(stat.Size()), j.VHDXOver), nil
+			}
+		}
+		// Vacuum terminates the distro, which would kill whatever is running in it
+		if busy := busyEngines(); len(busy) > 0 {
+			return fmt.Sprintf("skipped, containers are running (%s)", strings.Join(busy, ", ")), nil
+		}
+		res, err := Vacuum(VacuumOptions{})
+		if err != nil {
+			return "", err
+		}
+		return res.Summary(), nil
+	}
+	return "", fmt.Errorf("unknown task %q", j.Task)
+}
+
+// busyEngines lists the running engines that have containers up
+func busyEngines() []string {
+	var busy []string
+	for _, engine := range PruneEngineNames {
+		if !engineRunning(engine) {
+			continue
+		}
+		args := []string{engine, "ps", "-q"}
+		if engine == "k3s" {
+			args = []string{"k3s", "crictl", "ps", "-q"}
+		}
+		output, err := wslCommand(engineCommandArgs(engine, args...)...).Output()
+		// When in doubt, count the engine as busy
+		if err != nil || strings.TrimSpace(string(output)) != "" {
+			busy = append(busy, engine)
+		}
+	}
+	return busy
+}
+
+// RunDueMaintenance runs the active distro's overdue jobs, only the non-disruptive ones if asked.
+// It does nothing while another ezship process is already running maintenance.
+func RunDueMaintenance(nonDisruptive bool) ([]JobRun, error) {
+	l, err := TryAcquireLock(MaintenanceLock, "running scheduled maintenance")
+	if err != nil || l == nil {
+		return nil, err
+	}
+	defer l.Release()
+	s := NewScheduler(LoadConfig())
+	s.NonDisruptive = nonDisruptive
+	return s.RunDue()
+}
+
+// TriggerOverdueMaintenance starts 'ezship agent --once --non-disruptive' in the background when
+// a job is overdue or new. The proxy calls it after each command, so maintenance happens even
+// without a running agent; jobs that stop the distro are left to the agent.
+func TriggerOverdueMaintenance() {
+	cfg := LoadConfig()
+	if len(cfg.Maintenance) == 0 {
+		return
+	}
+	s := NewScheduler(cfg)
+	s.NonDisruptive = true
+	state := LoadMaintenanceState()
+	// New jobs are seeded by the agent, which saves the state under the lock
+	if !s.seed(&state) && len(s.Due(state)) == 0 {
+		return
+	}
+	// An agent is already working on it
+	l, err := TryAcquireLock(MaintenanceLock, "checking scheduled maintenance")
+	if err != nil || l == nil {
+		return
+	}
+	l.Release()
+
+	bin, err := ezshipBinary()
+	if err != nil {
+		return
+	}
+	cmd := exec.Command(bin, "agent", "--once", "--non-disruptive", "--profile", activeProfile)
+	cmd.SysProcAttr = detachedProcAttr()
+	if cmd.Start() == nil {
+		cmd.Process.Release()
+	}
+}
+
+// ezshipBinary returns the ezship executable, which sits next to the proxy aliases
+func ezshipBinary() (string, error) {
+	dir, self, err := aliasDir()
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(self)), ".exe")
+	if name == "ezship" {
+		return self, nil
+	}
+	bin := filepath.Join(dir, "ezship"+filepath.Ext(self))
+	if _, err := os.Stat(bin); err != nil {
+		return "", err
+	}
+	return bin, nil
+}
@@ -0,0 +1,214 @@
+package wsl
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable clock for the scheduler
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func date(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestScheduleDue(t *testing.T) {
+	// 2026-03-02 is a Monday
+	tests := []struct {
+		name      string
+		job       MaintenanceJob
+		last, now string
+		due       bool
+	}{
+		{"never run", MaintenanceJob{Task: TaskPrune, Every: "monday"}, "", "2026-03-04 12:00", true},
+		{"weekday before time", MaintenanceJob{Task: TaskPrune, Every: "monday"}, "2026-02-23 03:00", "2026-03-02 02:59", false},
+		{"weekday at time", MaintenanceJob{Task: TaskPrune, Every: "monday"}, "2026-02-23 03:00", "2026-03-02 03:00", true},
+		{"weekday overdue", MaintenanceJob{Task: TaskPrune, Every: "Monday", At: "09:30"}, "2026-02-23 09:30", "2026-03-05 08:00", true},
+		{"weekday done", MaintenanceJob{Task: TaskPrune, Every: "monday"}, "2026-03-02 03:01", "2026-03-08 23:00", false},
+		{"daily", MaintenanceJob{Task: TaskVacuum}, "2026-03-01 03:00", "2026-03-02 03:00", true},
+		{"daily done", MaintenanceJob{Task: TaskVacuum}, "2026-03-02 03:00", "2026-03-02 22:00", false},
+		{"interval", MaintenanceJob{Task: TaskVacuum, Every: "12h"}, "2026-03-02 01:00", "2026-03-02 12:59", false},
+		{"interval elapsed", MaintenanceJob{Task: TaskVacuum, Every: "12h"}, "2026-03-02 01:00", "2026-03-02 13:00", true},
+		{"days", MaintenanceJob{Task: TaskPrune, Every: "7d"}, "2026-03-02 01:00", "2026-03-08 01:00", false},
+	}
+	for _, tt := range tests {
+		sched, err := tt.job.schedule()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var last time.Time
+		if tt.last != "" {
+			last = date(tt.last)
+		}
+		if got := sched.due(last, date(tt.now)); got != tt.due {
+			t.Errorf("%s: due = %v; want %v", tt.name, got, tt.due)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	s := &Scheduler{Now: (&fakeClock{date("2026-03-04 12:00")}).Now}
+	state := MaintenanceState{Jobs: map[string]JobRun{
+		"prune-monday": {Time: date("2026-03-02 03:00")},
+		"vacuum-12h":   {Time: date("2026-03-04 10:00")},
+	}}
+	next, _ := s.NextRun(MaintenanceJob{Task: TaskPrune, Every: "monday"}, state)
+	if !next.Equal(date("2026-03-09 03:00")) {
+		t.Errorf("Expected next Monday, got %v", next)
+	}
+	next, _ = s.NextRun(MaintenanceJob{Task: TaskVacuum, Every: "12h"}, state)
+	if !next.Equal(date("2026-03-04 22:00")) {
+		t.Errorf("Expected 12h after the last run, got %v", next)
+	}
+}
+
+func TestMaintenanceJobValidate(t *testing.T) {
+	valid := []MaintenanceJob{
+		{Task: TaskPrune, Every: "monday", OlderThan: "7d", Engines: []string{"docker"}},
+		{Task: TaskVacuum, Every: "24h", VHDXOver: "40GB"},
+	}
+	for _, j := range valid {
+		if err := j.Validate(); err != nil {
+			t.Errorf("Unexpected error for %+v: %v", j, err)
+		}
+	}
+	invalid := []MaintenanceJob{
+		{Task: "defrag"},
+		{Task: TaskPrune, Every: "someday"},
+		{Task: TaskPrune, At: "25:00"},
+		{Task: TaskPrune, OlderThan: "a week"},
+		{Task: TaskPrune, Engines: []string{"k3d"}},
+		{Task: TaskVacuum, VHDXOver: "huge"},
+	}
+	for _, j := range invalid {
+		if err := j.Validate(); err == nil {
+			t.Errorf("Expected error for %+v", j)
+		}
+	}
+}
+
+func TestSchedulerRunDue(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	clock := &fakeClock{date("2026-03-02 08:00")}
+	var ran []string
+	s := &Scheduler{
+		Jobs: []MaintenanceJob{
+			{Task: TaskPrune, Every: "monday", OlderThan: "7d"},
+			{Name: "big-disk", Task: TaskVacuum, Every: "12h", VHDXOver: "40GB"},
+			{Task: "bogus"},
+		},
+		Now: clock.Now,
+		Run: func(j MaintenanceJob) (string, error) {
+			ran = append(ran, j.ID())
+			if j.Task == TaskVacuum {
+				return "", errors.New("diskpart needs an elevated terminal")
+			}
+			return "docker: reclaimed 1.0 GB", nil
+		},
+	}
+
+	// New jobs wait for their first slot rather than running at once
+	runs, err := s.RunDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != 0 || len(runs) != 0 {
+		t.Fatalf("Expected new jobs to wait, ran %v", ran)
+	}
+
+	// The state file makes the next check a no-op until a job is due
+	clock.now = date("2026-03-02 19:00")
+	s.RunDue()
+	if len(ran) != 0 {
+		t.Errorf("Expected nothing to run, ran %v", ran)
+	}
+	clock.now = date("2026-03-02 20:00")
+	s.RunDue()
+	if strings.Join(ran, ",") != "big-disk" {
+		t.Errorf("Expected only the 12h job 12 hours after it was added, ran %v", ran)
+	}
+
+	ran = nil
+	clock.now = date("2026-03-09 03:00")
+	runs, err = s.RunDue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(ran, ",") != "prune-monday,big-disk" || len(runs) != 2 {
+		t.Fatalf("Expected both valid jobs to run, ran %v", ran)
+	}
+	if runs[1].Error == "" {
+		t.Error("Expected the failure to be recorded")
+	}
+
+	state := LoadMaintenanceState()
+	if r := state.Jobs["prune-monday"]; r.Result != "docker: reclaimed 1.0 GB" || !r.Time.Equal(date("2026-03-09 03:00")) {
+		t.Errorf("Unexpected state for the prune job: %+v", r)
+	}
+}
+
+func TestSchedulerNonDisruptive(t *testing.T) {
+	clock := &fakeClock{date("2026-03-09 03:00")}
+	s := &Scheduler{
+		Jobs: []MaintenanceJob{
+			{Task: TaskPrune, Every: "monday"},
+			{Task: TaskVacuum, Every: "monday"},
+		},
+		Now:           clock.Now,
+		NonDisruptive: true,
+	}
+	state := MaintenanceState{Jobs: map[string]JobRun{
+		"prune-monday":  {Time: date("2026-03-02 03:00")},
+		"vacuum-monday": {Time: date("2026-03-02 03:00")},
+	}}
+	due := s.Due(state)
+	if len(due) != 1 || due[0].Task != TaskPrune {
+		t.Errorf("Expected only the prune job, got %+v", due)
+	}
+
+	// An unseeded job is reported, so the proxy starts the agent to record it
+	if !s.seed(&MaintenanceState{Jobs: map[string]JobRun{}}) || s.seed(&state) {
+		t.Error("Expected seed to report only new jobs")
+	}
+}
+
+func TestVacuumJobThreshold(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	os.MkdirAll(GetInstallDir(), 0755)
+	os.WriteFile(filepath.Join(GetInstallDir(), "ext4.vhdx"), make([]byte, 4096), 0644)
+
+	result, err := runMaintenanceJob(MaintenanceJob{Task: TaskVacuum, VHDXOver: "40GB"})
+	if err != nil || !strings.HasPrefix(result, "skipped") {
+		t.Errorf("Expected a small disk to be skipped, got %q, %v", result, err)
+	}
+}
+
+func TestVacuumJobSkipsBusyDistro(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	origWSL, origRunning := wslCommand, engineRunning
+	t.Cleanup(func() { wslCommand, engineRunning = origWSL, origRunning })
+	engineRunning = func(engine string) bool { return engine == "docker" }
+	wslCommand = func(args ...string) *exec.Cmd {
+		if strings.Contains(strings.Join(args, " "), "docker ps -q") {
+			return stubCommand("3f2a9c1b\n")
+		}
+		t.Errorf("Unexpected command %v", args)
+		return stubCommand("")
+	}
+
+	result, err := runMaintenanceJob(MaintenanceJob{Task: TaskVacuum})
+	if err != nil || result != "skipped, containers are running (docker)" {
+		t.Errorf("Expected the vacuum to be skipped, got %q, %v", result, err)
+	}
+}
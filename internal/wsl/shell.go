@@ -0,0 +1,62 @@
+package wsl
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// workingDir returns the current Windows directory. Tests swap it out.
+var workingDir = os.Getwd
+
+// distroWorkDir returns the current Windows directory as a path inside the distro,
+// falling back to the home directory when it has no /mnt equivalent (e.g. UNC paths)
+func distroWorkDir() string {
+	dir, err := workingDir()
+	if err != nil {
+		return "~"
+	}
+	translated := TranslatePath(dir)
+	if !strings.HasPrefix(translated, "/mnt/") {
+		return "~"
+	}
+	return translated
+}
+
+// ShellCommand builds an interactive login shell for a distro, starting in the current directory.
+// The caller wires up the streams (the TUI hands it to tea.ExecProcess).
+func ShellCommand(distro string, root bool) *exec.Cmd {
+	args := []string{"-d", distro, "--cd", distroWorkDir()}
+	if root {
+		args = append(args, "-u", "root")
+	}
+	return exec.Command("wsl", args...)
+}
+
+// ExecCommand builds a one-off command inside the ezship distro, with Windows paths translated
+func ExecCommand(command []string, root bool) *exec.Cmd {
+	args := []string{"-d", DistroName, "--cd", distroWorkDir()}
+	if root {
+		args = append(args, "-u", "root")
+	}
+	args = append(args, "-e")
+	args = append(args, TranslateArgs(command)...)
+	return exec.Command("wsl", args...)
+}
+
+// OpenShell opens an interactive shell in the ezship distro on the current console
+func OpenShell(root bool) error {
+	return runAttached(ShellCommand(DistroName, root))
+}
+
+// ExecInDistro runs a command in the ezship distro on the current console
+func ExecInDistro(command []string, root bool) error {
+	return runAttached(ExecCommand(command, root))
+}
+
+func runAttached(cmd *exec.Cmd) error {
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,63 @@
+package wsl
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestShellAndExecCommandArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		cwd   string
+		err   error
+		shell func() []string
+		want  []string
+	}{
+		{
+			name:  "shell in translated cwd",
+			cwd:   `C:\Users\dev\project`,
+			shell: func() []string { return ShellCommand("ezship-dev", false).Args },
+			want:  []string{"wsl", "-d", "ezship-dev", "--cd", "/mnt/c/Users/dev/project"},
+		},
+		{
+			name:  "root shell in translated cwd",
+			cwd:   `D:\work`,
+			shell: func() []string { return ShellCommand(DistroName, true).Args },
+			want:  []string{"wsl", "-d", DistroName, "--cd", "/mnt/d/work", "-u", "root"},
+		},
+		{
+			name:  "UNC cwd falls back to home",
+			cwd:   `\\server\share\project`,
+			shell: func() []string { return ShellCommand(DistroName, false).Args },
+			want:  []string{"wsl", "-d", DistroName, "--cd", "~"},
+		},
+		{
+			name:  "unreadable cwd falls back to home",
+			err:   errors.New("getwd failed"),
+			shell: func() []string { return ExecCommand([]string{"ls"}, false).Args },
+			want:  []string{"wsl", "-d", DistroName, "--cd", "~", "-e", "ls"},
+		},
+		{
+			name: "exec args with spaces stay whole",
+			cwd:  `C:\src`,
+			shell: func() []string {
+				return ExecCommand([]string{"cat", `C:\My Documents\notes.txt`, "hello world"}, true).Args
+			},
+			want: []string{"wsl", "-d", DistroName, "--cd", "/mnt/c/src", "-u", "root", "-e",
+				"cat", "/mnt/c/My Documents/notes.txt", "hello world"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := workingDir
+			workingDir = func() (string, error) { return tt.cwd, tt.err }
+			t.Cleanup(func() { workingDir = orig })
+
+			if got := tt.shell(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("args = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package wsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultSnapshotRetention is how many automatic snapshots per profile are kept when snapshot_retention is unset
+const DefaultSnapshotRetention = 3
+
+const snapshotCatalogName = "catalog.json"
+
+// SkipSnapshots disables automatic snapshots for this run (the --no-snapshot flag)
+var SkipSnapshots bool
+
+// Snapshot is an automatic export taken before a destructive operation
+type Snapshot struct {
+	BackupInfo
+	Reason string `json:"reason"`
+}
+
+// snapshotCatalog is the index of the snapshots directory
+type snapshotCatalog struct {
+	Snapshots []Snapshot `json:"snapshots"`
+}
+
+// GetSnapshotDir returns the directory holding automatic snapshots and their catalog
+func GetSnapshotDir(cfg Config) string {
+	return filepath.Join(GetBackupDir(cfg), "snapshots")
+}
+
+// snapshotRetention returns how many snapshots per profile to keep (0 keeps all), like backupRetention
+func snapshotRetention(cfg Config) int {
+	switch {
+	case cfg.SnapshotRetention == 0:
+		return DefaultSnapshotRetention
+	case cfg.SnapshotRetention < 0:
+		return 0
+	}
+	return cfg.SnapshotRetention
+}
+
+func loadSnapshotCatalog(dir string) (*snapshotCatalog, error) {
+	data, err := os.ReadFile(filepath.Join(dir, snapshotCatalogName))
+	if os.IsNotExist(err) {
+		return &snapshotCatalog{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c snapshotCatalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid snapshot catalog: %w", err)
+	}
+	return &c, nil
+}
+
+func (c *snapshotCatalog) save(dir string) error {
+	sort.Slice(c.Snapshots, func(i, j int) bool { return c.Snapshots[i].Created.After(c.Snapshots[j].Created) })
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Replace atomically: a torn catalog would hide every snapshot
+	tmp := filepath.Join(dir, snapshotCatalogName+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, snapshotCatalogName))
+}
+
+// forProfile returns the profile's snapshots, newest first
+func (c *snapshotCatalog) forProfile(profile string) []Snapshot {
+	var out []Snapshot
+	for _, s := range c.Snapshots {
+		if s.Profile == profile {
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.After(out[j].Created) })
+	return out
+}
+
+// prune drops the profile's snapshots beyond keep, deleting their archives
+func (c *snapshotCatalog) prune(dir, profile string, keep int) {
+	drop := make(map[string]bool)
+	for i, s := range c.forProfile(profile) {
+		if keep > 0 && i >= keep {
+			drop[s.Name] = true
+			os.Remove(s.ArchivePath(dir))
+		}
+	}
+	kept := c.Snapshots[:0]
+	for _, s := range c.Snapshots {
+		if !drop[s.Name] {
+			kept = append(kept, s)
+		}
+	}
+	c.Snapshots = kept
+}
+
+// Verify checks that the snapshot archive exists and matches its recorded checksum
+func (s Snapshot) Verify(dir string) error {
+	if s.SHA256 == "" {
+		return fmt.Errorf("snapshot %s has no checksum", s.Name)
+	}
+	if !verifyFile(s.ArchivePath(dir), s.SHA256) {
+		return fmt.Errorf("snapshot %s is missing or corrupt", s.Name)
+	}
+	return nil
+}
+
+// snapshotsEnabled reports whether destructive operations should take a snapshot first
+func snapshotsEnabled(cfg Config) bool {
+	return cfg.AutoSnapshot && !SkipSnapshots
+}
+
+// checkSnapshotSpace fails when the snapshot dir's disk has less than need bytes free, so a
+// snapshot never fills the host disk halfway through an export
+func checkSnapshotSpace(cfg Config, need int64) error {
+	if !snapshotsEnabled(cfg) || need <= 0 {
+		return nil
+	}
+	// The dir may not exist yet: ask about its closest existing parent
+	dir := GetSnapshotDir(cfg)
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	free, err := diskFree(dir)
+	if err != nil {
+		return nil // unknown: let the export find out
+	}
+	if free < need {
+		return fmt.Errorf("not enough space for a snapshot in %s: about %s needed, %s free (use --no-snapshot to skip it)",
+			GetSnapshotDir(cfg), FormatSize(need), FormatSize(free))
+	}
+	return nil
+}
+
+// takeSnapshot exports the active distro before a destructive operation when the auto_snapshot
+// policy is on. Nothing is done if the distro is not installed. The caller holds the operation lock
+// if the operation needs one.
+func takeSnapshot(reason string) error {
+	cfg := LoadConfig()
+	if !snapshotsEnabled(cfg) {
+		return nil
+	}
+	if installed, _ := IsDistroInstalled(); !installed {
+		return nil
+	}
+
+	dir := GetSnapshotDir(cfg)
+	fmt.Fprintf(os.Stderr, "Taking snapshot of %s before %s (skip with --no-snapshot)...\n", DistroName, reason)
+	name := activeProfile + "-" + time.Now().Format("20060102-150405.000")
+	info, err := exportBackup(cfg, dir, name)
+	if err != nil {
+		return fmt.Errorf("snapshot before %s failed (use --no-snapshot to skip it): %w", reason, err)
+	}
+
+	catalog, err := loadSnapshotCatalog(dir)
+	if err != nil {
+		catalog = &snapshotCatalog{} // start over rather than block the operation
+	}
+	catalog.Snapshots = append(catalog.Snapshots, Snapshot{BackupInfo: *info, Reason: reason})
+	catalog.prune(dir, activeProfile, snapshotRetention(cfg))
+	return catalog.save(dir)
+}
+
+// ListSnapshots returns the snapshots of the active profile, newest first
+func ListSnapshots(cfg Config) ([]Snapshot, error) {
+	catalog, err := loadSnapshotCatalog(GetSnapshotDir(cfg))
+	if err != nil {
+		return nil, err
+	}
+	return catalog.forProfile(activeProfile), nil
+}
+
+// RollbackSnapshot replaces the active distro with its most recent intact snapshot.
+// Corrupt snapshots are skipped with a warning.
+func RollbackSnapshot() (*Snapshot, error) {
+	var restored *Snapshot
+	err := withLock(OperationLock, "rolling back to snapshot", func() error {
+		var err error
+		restored, err = rollbackSnapshot()
+		return err
+	})
+	return restored, err
+}
+
+func rollbackSnapshot() (*Snapshot, error) {
+	cfg := LoadConfig()
+	dir := GetSnapshotDir(cfg)
+	snapshots, err := ListSnapshots(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("no snapshots for profile %s (enable auto_snapshot in the config)", activeProfile)
+	}
+
+	var snap *Snapshot
+	var broken []string
+	for i := range snapshots {
+		if err := snapshots[i].Verify(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v, trying an older one\n", err)
+			broken = append(broken, snapshots[i].Name)
+			continue
+		}
+		snap = &snapshots[i]
+		break
+	}
+	if snap == nil {
+		return nil, fmt.Errorf("all snapshots failed the integrity check: %s", strings.Join(broken, ", "))
+	}
+
+	if installed, _ := IsDistroInstalled(); installed {
+		if err := unregisterDistro(); err != nil {
+			return nil, err
+		}
+	}
+	installDir := GetInstallDir()
+	base, err := GetBaseImage(snap.BaseImage)
+	if err != nil {
+		base = CurrentBaseImage(cfg)
+	}
+	if err := importDistro(installDir, snap.ArchivePath(dir), base); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
@@ -0,0 +1,149 @@
+package wsl
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func enableSnapshots(t *testing.T, retention int) {
+	t.Helper()
+	if err := SaveConfig(Config{AutoSnapshot: true, SnapshotRetention: retention}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTakeSnapshotPolicy(t *testing.T) {
+	calls := stubDistroBackend(t, "ezship\r\n")
+
+	// Off by default
+	if err := takeSnapshot("reset"); err != nil {
+		t.Fatal(err)
+	}
+	if snaps, _ := ListSnapshots(LoadConfig()); len(snaps) != 0 || len(calls.Args()) != 0 {
+		t.Fatalf("Expected no snapshot without auto_snapshot, got %v", snaps)
+	}
+
+	enableSnapshots(t, 2)
+	SkipSnapshots = true
+	takeSnapshot("reset")
+	SkipSnapshots = false
+	if snaps, _ := ListSnapshots(LoadConfig()); len(snaps) != 0 {
+		t.Fatal("Expected --no-snapshot to skip the snapshot")
+	}
+
+	for _, reason := range []string{"prune", "vacuum", "reset"} {
+		if err := takeSnapshot(reason); err != nil {
+			t.Fatalf("takeSnapshot(%s) failed: %v", reason, err)
+		}
+		time.Sleep(2 * time.Millisecond) // distinct names
+	}
+
+	cfg := LoadConfig()
+	snaps, err := ListSnapshots(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 2 || snaps[0].Reason != "reset" || snaps[1].Reason != "vacuum" {
+		t.Fatalf("Expected the two newest snapshots to be kept, got %+v", snaps)
+	}
+	for _, s := range snaps {
+		if err := s.Verify(GetSnapshotDir(cfg)); err != nil {
+			t.Errorf("Expected snapshot to verify: %v", err)
+		}
+	}
+}
+
+func TestResetTakesSnapshot(t *testing.T) {
+	calls := stubDistroBackend(t, "ezship\r\n")
+	enableSnapshots(t, 0)
+
+	if err := ResetDistro(); err != nil {
+		t.Fatalf("ResetDistro failed: %v", err)
+	}
+	var exported, unregistered int
+	for i, c := range calls.Args() {
+		switch c[0] {
+		case "--export":
+			exported = i
+		case "--unregister":
+			unregistered = i
+		}
+	}
+	if exported == 0 || unregistered < exported {
+		t.Errorf("Expected the export to run before the unregister, got %v", calls.Args())
+	}
+}
+
+func TestRollbackSnapshot(t *testing.T) {
+	calls := stubDistroBackend(t, "ezship\r\n")
+	enableSnapshots(t, 0)
+
+	if _, err := RollbackSnapshot(); err == nil {
+		t.Error("Expected error without snapshots")
+	}
+
+	takeSnapshot("prune")
+	time.Sleep(2 * time.Millisecond)
+	takeSnapshot("vacuum")
+
+	cfg := LoadConfig()
+	snaps, _ := ListSnapshots(cfg)
+	// Corrupt the newest: rollback must fall back to the older, intact one
+	os.WriteFile(snaps[0].ArchivePath(GetSnapshotDir(cfg)), []byte("truncated"), 0644)
+
+	snap, err := RollbackSnapshot()
+	if err != nil {
+		t.Fatalf("RollbackSnapshot failed: %v", err)
+	}
+	if snap.Reason != "prune" {
+		t.Errorf("Expected rollback to the intact prune snapshot, got %s", snap.Reason)
+	}
+
+	args := calls.Args()
+	n := len(args)
+	if args[n-2][0] != "--unregister" || args[n-1][0] != "--import" {
+		t.Errorf("Expected unregister then import, got %v", args[n-2:])
+	}
+
+	// Rolling back must not take a snapshot of its own
+	if after, _ := ListSnapshots(LoadConfig()); len(after) != 2 {
+		t.Errorf("Expected 2 snapshots after rollback, got %d", len(after))
+	}
+}
+
+func TestSnapshotRetention(t *testing.T) {
+	if got := snapshotRetention(Config{}); got != DefaultSnapshotRetention {
+		t.Errorf("Expected the default, got %d", got)
+	}
+	// Negative keeps all, like backup_retention
+	if got, want := snapshotRetention(Config{SnapshotRetention: -1}), backupRetention(Config{BackupRetention: -1}); got != want || got != 0 {
+		t.Errorf("Expected negative retention to keep all, got %d", got)
+	}
+
+	stubDistroBackend(t, "ezship\r\n")
+	enableSnapshots(t, -1)
+	for _, reason := range []string{"prune", "upgrading docker", "reset", "prune"} {
+		takeSnapshot(reason)
+		time.Sleep(2 * time.Millisecond) // distinct names
+	}
+	if snaps, _ := ListSnapshots(LoadConfig()); len(snaps) != 4 {
+		t.Errorf("Expected every snapshot to be kept, got %d", len(snaps))
+	}
+}
+
+func TestCheckSnapshotSpace(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	cfg := Config{AutoSnapshot: true}
+	if err := checkSnapshotSpace(cfg, 1<<10); err != nil {
+		t.Errorf("Expected room for a small snapshot: %v", err)
+	}
+	err := checkSnapshotSpace(cfg, 1<<62)
+	if err == nil || !strings.Contains(err.Error(), "--no-snapshot") {
+		t.Errorf("Expected a clear error for a snapshot that cannot fit, got %v", err)
+	}
+	if err := checkSnapshotSpace(Config{}, 1<<62); err != nil {
+		t.Errorf("Expected no check without auto_snapshot, got %v", err)
+	}
+}
@@ -64,6 +64,16 @@ func GetEngineStatus(engine string) EngineInfo {
 			socketPath = "/run/docker.sock"
 		}
 
+		// Rootless podman has no service: it is usable once 'podman info' works for the user
+		if engine == "podman" && !defaultUserIsRoot() {
+			if wslCommand("-d", DistroName, "-e", "podman", "info").Run() == nil {
+				mu.Lock()
+				info.Running = true
+				mu.Unlock()
+			}
+			return
+		}
+
 		statusCmd := exec.Command("wsl", "-d", DistroName, "pgrep", "-x", daemonName)
 		if err := statusCmd.Run(); err == nil {
 			socketCheck := exec.Command("wsl", "-d", DistroName, "ls", socketPath)
@@ -0,0 +1,156 @@
+package wsl
+
+import (
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// ttyValueFlags are run/exec options that consume the following argument, so the
+// scan for -t does not mistake their values for the image or container name
+var ttyValueFlags = map[string]bool{
+	"-e": true, "--env": true, "--env-file": true, "-v": true, "--volume": true, "--mount": true,
+	"-p": true, "--publish": true, "--name": true, "-w": true, "--workdir": true, "-u": true,
+	"--user": true, "--network": true, "--entrypoint": true, "-l": true, "--label": true,
+	"--platform": true, "-h": true, "--hostname": true, "--add-host": true, "--cpus": true,
+	"-m": true, "--memory": true, "--restart": true, "--device": true, "--cap-add": true,
+	"--cap-drop": true, "--security-opt": true, "--pull": true, "--log-driver": true,
+	"--log-opt": true, "--tmpfs": true, "--ulimit": true, "--dns": true, "--gpus": true,
+	"--shm-size": true, "--detach-keys": true, "--pod": true,
+}
+
+// boolShortFlags are the value-less short options that may be combined with -t (e.g. -dit)
+const boolShortFlags = "itdPq"
+
+// isTerminal reports whether f is attached to a console (or a Cygwin/MSYS pty)
+func isTerminal(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	fd := f.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// stripTTYFlag removes the tty request from a single option, returning "" if nothing is left.
+// Combined short flags keep their other letters, e.g. "-it" becomes "-i".
+func stripTTYFlag(arg string) (string, bool) {
+	switch {
+	case arg == "--tty" || strings.HasPrefix(arg, "--tty="):
+		return "", true
+	case strings.HasPrefix(arg, "--") || !strings.HasPrefix(arg, "-") || len(arg) < 2:
+		return arg, false
+	case !strings.Contains(arg[1:], "t") || strings.Trim(arg[1:], boolShortFlags) != "":
+		// Only touch clusters of boolean flags, so values like "-wtmp" survive
+		return arg, false
+	}
+
+	rest := strings.ReplaceAll(arg[1:], "t", "")
+	if rest == "" {
+		return "", true
+	}
+	return "-" + rest, true
+}
+
+// detached reports whether run/exec options (up to the image or container name) request
+// a detached container, e.g. "-d", "-dit" or "--detach"
+func detached(opts []string) bool {
+	for i := 0; i < len(opts); i++ {
+		arg := opts[i]
+		switch {
+		case !strings.HasPrefix(arg, "-") || arg == "--":
+			return false
+		case arg == "--detach" || arg == "--detach=true":
+			return true
+		case !strings.HasPrefix(arg, "--") && strings.Contains(arg[1:], "d") && strings.Trim(arg[1:], boolShortFlags) == "":
+			return true
+		case ttyValueFlags[arg]:
+			i++
+		}
+	}
+	return false
+}
+
+// AdjustTTYArgs drops TTY allocation flags (-t, -it, --tty) from run/exec style commands
+// when the Windows side is not a console, e.g. 'docker exec -it db psql < dump.sql'.
+// Without this the engine refuses with "the input device is not a TTY".
+func AdjustTTYArgs(tool string, args []string, console bool) []string {
+	if console {
+		return args
+	}
+
+	switch tool {
+	case "kubectl":
+		// kubectl accepts flags anywhere before "--"
+		if len(args) == 0 || (args[0] != "exec" && args[0] != "run" && args[0] != "attach") {
+			return args
+		}
+		var out []string
+		for i, arg := range args {
+			if arg == "--" {
+				return append(out, args[i:]...)
+			}
+			if stripped, ok := stripTTYFlag(arg); ok {
+				if stripped != "" {
+					out = append(out, stripped)
+				}
+				continue
+			}
+			out = append(out, arg)
+		}
+		return out
+
+	case "docker", "podman", "nerdctl":
+		// Find the run/exec subcommand, then scan its options up to the image/container name
+		start := -1
+		for i, arg := range args {
+			if arg == "run" || arg == "exec" {
+				start = i + 1
+				break
+			}
+			if !strings.HasPrefix(arg, "-") && arg != "container" {
+				return args
+			}
+		}
+		if start < 0 || detached(args[start:]) {
+			// A detached container keeps its TTY for later 'attach'; it does not use ours
+			return args
+		}
+
+		out := append([]string{}, args[:start]...)
+		for i := start; i < len(args); i++ {
+			arg := args[i]
+			if !strings.HasPrefix(arg, "-") || arg == "--" {
+				return append(out, args[i:]...)
+			}
+			if stripped, ok := stripTTYFlag(arg); ok {
+				if stripped != "" {
+					out = append(out, stripped)
+				}
+				continue
+			}
+			out = append(out, arg)
+			if ttyValueFlags[arg] && i+1 < len(args) {
+				i++
+				out = append(out, args[i])
+			}
+		}
+		return out
+	}
+
+	return args
+}
+
+// requestsTTY reports whether a run/exec style command asks for a TTY (-t, -it, --tty)
+func requestsTTY(tool string, args []string) bool {
+	adjusted := AdjustTTYArgs(tool, args, false)
+	if len(adjusted) != len(args) {
+		return true
+	}
+	for i := range args {
+		if adjusted[i] != args[i] {
+			return true
+		}
+	}
+	return false
+}
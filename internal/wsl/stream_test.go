@@ -0,0 +1,36 @@
+package wsl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAdjustTTYArgs(t *testing.T) {
+	tests := []struct {
+		tool    string
+		args    string
+		console bool
+		want    string
+	}{
+		{"docker", "exec -it db psql", false, "exec -i db psql"},
+		{"docker", "exec -it db psql", true, "exec -it db psql"},
+		{"docker", "run --rm -t -e X=1 -w /tmp alpine ls -t", false, "run --rm -e X=1 -w /tmp alpine ls -t"},
+		{"docker", "run -dit --name -t alpine", false, "run -dit --name -t alpine"},
+		{"docker", "run --detach -it ubuntu", false, "run --detach -it ubuntu"},
+		{"docker", "run -it --name d ubuntu", false, "run -i --name d ubuntu"},
+		{"docker", "run -wtmp alpine", false, "run -wtmp alpine"},
+		{"docker", "container exec --tty=true db sh", false, "container exec db sh"},
+		{"docker", "ps -a", false, "ps -a"},
+		{"podman", "save -o img.tar img", false, "save -o img.tar img"},
+		{"kubectl", "exec -it db -- psql -t", false, "exec -i db -- psql -t"},
+		{"kubectl", "get pods -t", false, "get pods -t"},
+		{"helm", "install -t x", false, "install -t x"},
+	}
+
+	for _, tt := range tests {
+		got := strings.Join(AdjustTTYArgs(tt.tool, strings.Fields(tt.args), tt.console), " ")
+		if got != tt.want {
+			t.Errorf("AdjustTTYArgs(%s, %q) = %q; want %q", tt.tool, tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package wsl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UninstallOptions selects what 'ezship uninstall' leaves behind
+type UninstallOptions struct {
+	KeepConfig  bool // keep config.json
+	KeepBackups bool // keep backups and snapshots
+}
+
+// UninstallItem is something ezship created on the host, with the way to remove it
+type UninstallItem struct {
+	Kind        string // distro, kubeconfig, alias, path, backups, audit-log, data, binary
+	Description string
+	remove      func() error
+}
+
+// Remove deletes the item
+func (i UninstallItem) Remove() error {
+	return i.remove()
+}
+
+// installScriptDir is where install.ps1 puts ezship.exe and adds it to the user PATH
+func installScriptDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ezship", "bin")
+}
+
+// GetDataDir returns ezship's directory under %APPDATA% (config, downloads, state, default disks)
+func GetDataDir() string {
+	return filepath.Dir(GetConfigPath())
+}
+
+// isWithin reports whether path is dir or inside it
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// dirSize adds up the size of the files under dir
+func dirSize(dir string) int64 {
+	var n int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				n += info.Size()
+			}
+		}
+		return nil
+	})
+	return n
+}
+
+// removePathEntry drops dir from a PATH value, reporting whether it was there
+func removePathEntry(path, dir string) (string, bool) {
+	clean := strings.TrimRight(dir, `\/`)
+	var kept []string
+	found := false
+	for _, entry := range strings.Split(path, ";") {
+		if strings.EqualFold(strings.TrimRight(strings.TrimSpace(entry), `\/`), clean) {
+			found = true
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	return strings.Join(kept, ";"), found
+}
+
+// registeredEzshipDistros returns the registered WSL distros of the config's profiles. A distro
+// merely named like one ("ezship-old") that no profile knows about is not ezship's to remove.
+func registeredEzshipDistros(cfg Config) []string {
+	output, err := wslCommand("--list", "--quiet").Output()
+	if err != nil {
+		return nil
+	}
+	profiles := make(map[string]bool)
+	for _, p := range ListProfiles(cfg) {
+		profiles[strings.ToLower(p.Distro)] = true
+	}
+	var names []string
+	for _, line := range strings.Split(strings.ReplaceAll(string(output), "\x00", ""), "\n") {
+		name := strings.TrimSpace(line)
+		if profiles[strings.ToLower(name)] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// k3dClusters returns the names of the k3d clusters in the distros. k3d needs the distro's
+// docker running; clusters it cannot list are left out, so their contexts are kept.
+func k3dClusters(distros []string) []string {
+	var names []string
+	for _, distro := range distros {
+		output, err := wslCommand("-d", distro, "-e", "k3d", "cluster", "list", "-o", "json").Output()
+		if err != nil {
+			continue
+		}
+		var clusters []struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(output, &clusters) != nil {
+			continue
+		}
+		for _, c := range clusters {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// PlanUninstall enumerates everything ezship created on the host, in removal order
+func PlanUninstall(opts UninstallOptions) []UninstallItem {
+	cfg := LoadConfig()
+	dataDir := GetDataDir()
+	var items []UninstallItem
+	distros := registeredEzshipDistros(cfg)
+
+	// Kubeconfig contexts first: they only make sense while the clusters exist
+	clusters := k3dClusters(distros)
+	for _, path := range kubeconfigPaths() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		contexts := ezshipKubeContexts(string(data), clusters)
+		if len(contexts) == 0 {
+			continue
+		}
+		items = append(items, UninstallItem{
+			Kind:        "kubeconfig",
+			Description: fmt.Sprintf("kubeconfig contexts %s in %s", strings.Join(contexts, ", "), path),
+			remove: func() error {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				return os.WriteFile(path, []byte(removeKubeContexts(string(data), contexts)), 0600)
+			},
+		})
+	}
+
+	// Distros of every profile; unregistering deletes their disks
+	installDirs := make(map[string]string)
+	for _, p := range ListProfiles(cfg) {
+		installDirs[strings.ToLower(p.Distro)] = p.InstallDir
+	}
+	for _, distro := range distros {
+		dir := installDirs[strings.ToLower(distro)]
+		desc := "WSL distro " + distro
+		if dir != "" {
+			desc += " (disk in " + dir + ")"
+		}
+		items = append(items, UninstallItem{
+			Kind:        "distro",
+			Description: desc,
+			remove: func() error {
+				if output, err := wslCommand("--unregister", distro).CombinedOutput(); err != nil {
+					return fmt.Errorf("failed to unregister %s: %s (%w)", distro, strings.TrimSpace(string(output)), err)
+				}
+				if dir != "" {
+					os.Remove(dir) // custom install dirs, if nothing else is in them
+				}
+				return nil
+			},
+		})
+	}
+
+	// Proxy aliases (never foreign binaries such as a real docker.exe)
+	if aliases, err := ListAliases(); err == nil {
+		for _, a := range aliases {
+			if a.State != AliasCurrent && a.State != AliasStale {
+				continue
+			}
+			path := a.Path
+			items = append(items, UninstallItem{
+				Kind:        "alias",
+				Description: "proxy alias " + path,
+				remove:      func() error { return os.Remove(path) },
+			})
+		}
+	}
+
+	// The PATH entry install.ps1 added
+	binDir := installScriptDir()
+	if path, err := userPath(); err == nil && binDir != "" {
+		if _, found := removePathEntry(path, binDir); found {
+			items = append(items, UninstallItem{
+				Kind:        "path",
+				Description: binDir + " in the user PATH",
+				remove: func() error {
+					path, err := userPath()
+					if err != nil {
+						return err
+					}
+					newPath, _ := removePathEntry(path, binDir)
+					return setUserPath(newPath)
+				},
+			})
+		}
+	}
+
+	// Backups and snapshots outside the data dir are removed file by file: the directory may be shared
+	backupDir := GetBackupDir(cfg)
+	if !opts.KeepBackups {
+		if backups, _ := listBackups(backupDir); len(backups) > 0 || dirSize(GetSnapshotDir(cfg)) > 0 {
+			items = append(items, UninstallItem{
+				Kind:        "backups",
+				Description: fmt.Sprintf("%d backups and the snapshots in %s (%s)", len(backups), backupDir, FormatSize(dirSize(backupDir))),
+				remove: func() error {
+					for _, b := range backups {
+						removeBackup(backupDir, b)
+					}
+					if err := os.RemoveAll(GetSnapshotDir(cfg)); err != nil {
+						return err
+					}
+					os.Remove(backupDir)
+					return nil
+				},
+			})
+		}
+	}
+
+	if cfg.AuditLogPath != "" && !isWithin(cfg.AuditLogPath, dataDir) {
+		if _, err := os.Stat(cfg.AuditLogPath); err == nil {
+			path := cfg.AuditLogPath
+			items = append(items, UninstallItem{
+				Kind:        "audit-log",
+				Description: "audit log " + path,
+				remove:      func() error { return os.Remove(path) },
+			})
+		}
+	}
+
+	// The data dir: config, downloaded rootfs, state, logs and the default disks
+	if entries, err := os.ReadDir(dataDir); err == nil && len(entries) > 0 {
+		configPath := GetConfigPath()
+		keepBackupDir := opts.KeepBackups && isWithin(backupDir, dataDir)
+		var kept []string
+		if opts.KeepConfig {
+			kept = append(kept, "config.json")
+		}
+		if keepBackupDir {
+			kept = append(kept, "backups")
+		}
+		desc := fmt.Sprintf("ezship data in %s (%s)", dataDir, FormatSize(dirSize(dataDir)))
+		if len(kept) > 0 {
+			desc += ", keeping " + strings.Join(kept, " and ")
+		}
+		items = append(items, UninstallItem{
+			Kind:        "data",
+			Description: desc,
+			remove: func() error {
+				entries, err := os.ReadDir(dataDir)
+				if err != nil {
+					return err
+				}
+				for _, e := range entries {
+					path := filepath.Join(dataDir, e.Name())
+					if opts.KeepConfig && path == configPath {
+						continue
+					}
+					if keepBackupDir && isWithin(backupDir, path) {
+						// Clear everything beside the backups on the way down
+						if path != backupDir {
+							clearExcept(path, backupDir)
+						}
+						continue
+					}
+					if err := os.RemoveAll(path); err != nil {
+						return err
+					}
+				}
+				os.Remove(dataDir)
+				return nil
+			},
+		})
+	}
+
+	// The binary itself, when it is the one install.ps1 put in place
+	if exe, err := os.Executable(); err == nil && binDir != "" && strings.EqualFold(filepath.Dir(exe), binDir) {
+		items = append(items, UninstallItem{
+			Kind:        "binary",
+			Description: "ezship itself (" + exe + ")",
+			remove:      func() error { return deleteAfterExit(exe, binDir) },
+		})
+	}
+	return items
+}
+
+// clearExcept removes everything under dir except keep and its parents
+func clearExcept(dir, keep string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		switch {
+		case path == keep:
+		case isWithin(keep, path):
+			clearExcept(path, keep)
+		default:
+			os.RemoveAll(path)
+		}
+	}
+}
+
+// Uninstall removes the planned items, carrying on past failures. It returns the failures.
+// No lock is taken: the lock files live in the data dir being removed.
+func Uninstall(items []UninstallItem) []error {
+	var errs []error
+	for _, item := range items {
+		if err := item.Remove(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", item.Description, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,102 @@
+package wsl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRemovePathEntry(t *testing.T) {
+	path := `C:\Windows;C:\Users\me\.ezship\bin\;C:\tools`
+	got, found := removePathEntry(path, `c:\users\me\.ezship\bin`)
+	if !found || got != `C:\Windows;C:\tools` {
+		t.Errorf("removePathEntry() = %q, %v", got, found)
+	}
+	if _, found := removePathEntry(`C:\Windows`, `C:\Users\me\.ezship\bin`); found {
+		t.Error("Expected no match")
+	}
+}
+
+// stubUninstall sets up a home, data dir and distro list, returning the recorded wsl calls
+func stubUninstall(t *testing.T, cfg Config) *wslRecorder {
+	t.Helper()
+	calls := stubWSL(t,
+		stubResponse{match: "--list", output: "Ubuntu\nezship\nezship-work\nezship-old\n"},
+		stubResponse{match: "-d ezship-work -e k3d cluster list", output: `[{"name":"dev","serversRunning":1}]`},
+	)
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("KUBECONFIG", "")
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(GetDataDir(), "state"), 0755)
+	os.WriteFile(filepath.Join(GetDataDir(), "state", "ready-ezship-docker"), nil, 0644)
+	os.WriteFile(filepath.Join(GetDataDir(), "ubuntu-noble-wsl-amd64-ubuntu.rootfs.tar.gz"), []byte("rootfs"), 0644)
+	backupDir := GetBackupDir(cfg)
+	os.MkdirAll(backupDir, 0755)
+	writeBackupInfo(backupDir, &BackupInfo{Name: "default-1", Profile: DefaultProfile})
+	os.WriteFile(filepath.Join(backupDir, "default-1"+backupExt), []byte("archive"), 0644)
+
+	return calls
+}
+
+func TestUninstall(t *testing.T) {
+	calls := stubUninstall(t, Config{DefaultEngine: "docker", Profiles: map[string]ProfileConfig{"work": {}}})
+	kubeDir := filepath.Join(os.Getenv("HOME"), ".kube")
+	os.MkdirAll(kubeDir, 0755)
+	os.WriteFile(filepath.Join(kubeDir, "config"), []byte(sampleKubeconfig), 0600)
+
+	items := PlanUninstall(UninstallOptions{})
+	kinds := make(map[string]int)
+	for _, item := range items {
+		kinds[item.Kind]++
+	}
+	if kinds["distro"] != 2 || kinds["kubeconfig"] != 1 || kinds["backups"] != 1 || kinds["data"] != 1 {
+		t.Fatalf("Unexpected plan: %+v", kinds)
+	}
+
+	if errs := Uninstall(items); len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	unregistered := strings.Join(calls.Calls(), "\n")
+	if !strings.Contains(unregistered, "--unregister ezship\n") || !strings.Contains(unregistered, "--unregister ezship-work") || strings.Contains(unregistered, "Ubuntu") {
+		t.Errorf("Expected only ezship's distros to be unregistered, got:\n%s", unregistered)
+	}
+	if strings.Contains(unregistered, "ezship-old") {
+		t.Errorf("Expected a distro no profile knows to be left alone, got:\n%s", unregistered)
+	}
+	if _, err := os.Stat(GetDataDir()); !os.IsNotExist(err) {
+		t.Error("Expected the data dir to be removed")
+	}
+	data, _ := os.ReadFile(filepath.Join(kubeDir, "config"))
+	if strings.Contains(string(data), "k3d-dev") || !strings.Contains(string(data), "prod") {
+		t.Errorf("Expected only the k3d context to be removed:\n%s", data)
+	}
+}
+
+func TestUninstallKeep(t *testing.T) {
+	stubUninstall(t, Config{DefaultEngine: "podman"})
+
+	items := PlanUninstall(UninstallOptions{KeepConfig: true, KeepBackups: true})
+	for _, item := range items {
+		if item.Kind == "backups" {
+			t.Error("Backups must not be planned for removal with --keep-backups")
+		}
+	}
+	if errs := Uninstall(items); len(errs) != 0 {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+
+	if cfg := LoadConfig(); cfg.DefaultEngine != "podman" {
+		t.Error("Expected the config to be kept")
+	}
+	if _, err := os.Stat(filepath.Join(GetBackupDir(LoadConfig()), "default-1"+backupExt)); err != nil {
+		t.Error("Expected the backup to be kept")
+	}
+	for _, gone := range []string{"state", "ubuntu-noble-wsl-amd64-ubuntu.rootfs.tar.gz"} {
+		if _, err := os.Stat(filepath.Join(GetDataDir(), gone)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", gone)
+		}
+	}
+}
@@ -79,6 +79,13 @@ func SelfUpdate(currentVersion string) error {
 		return fmt.Errorf("failed to apply update: %w", err)
 	}
 
+	// Keep the docker.exe/kubectl.exe copies in sync with the new binary
+	if repaired, err := RepairAliases(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	} else if len(repaired) > 0 {
+		fmt.Printf("Refreshed aliases: %s\n", strings.Join(repaired, ", "))
+	}
+
 	fmt.Printf("Successfully updated to %s! Please restart ezship.\n", release.TagName)
 	return nil
 }
@@ -0,0 +1,165 @@
+package wsl
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// fallbackDistroUser is used when the Windows account name has nothing usable in a Linux name
+const fallbackDistroUser = "ezship"
+
+// rootEngines are reachable by root only: containerd's socket and k3s' kubeconfig are root-owned
+var rootEngines = map[string]bool{"nerdctl": true, "k3s": true, "kubectl": true}
+
+// windowsUserName returns the name of the Windows account running ezship
+func windowsUserName() string {
+	if name := os.Getenv("USERNAME"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		// DOMAIN\name on Windows
+		return u.Username[strings.LastIndex(u.Username, `\`)+1:]
+	}
+	return ""
+}
+
+// linuxUserName turns an account name into a valid Linux user name ("" if nothing is left)
+func linuxUserName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r == '_':
+			b.WriteRune(r)
+		case (r >= '0' && r <= '9') || r == '-':
+			if b.Len() == 0 {
+				b.WriteRune('_') // names cannot start with a digit or a dash
+			}
+			b.WriteRune(r)
+		case r == ' ' || r == '.':
+			if b.Len() > 0 {
+				b.WriteRune('-')
+			}
+		}
+	}
+	return strings.TrimRight(truncate(b.String(), 32), "-")
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// DistroUser returns the distro's default user: distro_user from the config, or the Windows
+// account name. "root" turns provisioning off.
+func DistroUser(cfg Config) string {
+	name := cfg.DistroUser
+	if name == "" {
+		name = windowsUserName()
+	}
+	if name = linuxUserName(name); name == "" {
+		return fallbackDistroUser
+	}
+	return name
+}
+
+// isSystemUID reports whether a uid belongs to a system account (daemon, www-data, nobody, ...)
+func isSystemUID(uid int) bool {
+	return uid < 1000 || uid >= 60000
+}
+
+// defaultUserIsRoot reports whether the distro's default user is root, i.e. it has no
+// provisioned user (distro_user "root", or a distro created before users were)
+func defaultUserIsRoot() bool {
+	output, err := wslCommand("-d", DistroName, "-e", "id", "-u").Output()
+	return err != nil || strings.TrimSpace(string(output)) == "0"
+}
+
+// engineCommandArgs returns the wsl.exe arguments running an engine command. Root-only engines
+// run as root; docker and podman run as the default user, which is in the docker group and
+// keeps its rootless podman containers in its home.
+func engineCommandArgs(engine string, args ...string) []string {
+	full := []string{"-d", DistroName}
+	if rootEngines[engine] {
+		full = append(full, "-u", "root")
+	}
+	return append(append(full, "-e"), args...)
+}
+
+// userSetupCommand returns the shell command creating the user, adding it to the docker group
+// and preparing rootless podman (subordinate ids, and no systemd in the distro)
+func userSetupCommand(name string, base BaseImage) string {
+	home := "/home/" + name
+	return fmt.Sprintf(base.packageManager().addUser, name) +
+		fmt.Sprintf(" && for f in /etc/subuid /etc/subgid; do grep -q '^%[1]s:' $f 2>/dev/null || echo '%[1]s:100000:65536' >> $f; done", name) +
+		" && mkdir -p " + home + "/.config/containers" +
+		` && printf '[engine]\ncgroup_manager = "cgroupfs"\nevents_logger = "file"\n' > ` + home + "/.config/containers/containers.conf" +
+		" && chown -R " + name + ":" + name + " " + home + "/.config"
+}
+
+// setWSLConfUser sets the default user in the [user] section of a wsl.conf, keeping the rest
+func setWSLConfUser(conf, name string) string {
+	var out []string
+	section, done := "", false
+	for _, line := range strings.Split(strings.TrimRight(conf, "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			if section == "user" && !done {
+				out = append(out, "default="+name)
+				done = true
+			}
+			section = strings.ToLower(strings.Trim(trimmed, "[]"))
+		} else if section == "user" && strings.HasPrefix(strings.ReplaceAll(trimmed, " ", ""), "default=") {
+			if !done {
+				out = append(out, "default="+name)
+				done = true
+			}
+			continue
+		}
+		out = append(out, line)
+	}
+	if !done {
+		if section != "user" {
+			out = append(out, "", "[user]")
+		}
+		out = append(out, "default="+name)
+	}
+	return strings.TrimLeft(strings.Join(out, "\n"), "\n") + "\n"
+}
+
+// provisionUser creates the distro's non-root default user. Proxied commands then run
+// unprivileged, so files they create in bind mounts belong to the user.
+func provisionUser(base BaseImage) error {
+	name := DistroUser(LoadConfig())
+	if name == "root" {
+		return nil
+	}
+	// An existing system account is never made the default user or added to the docker group
+	if output, err := wslCommand("-d", DistroName, "-u", "root", "-e", "id", "-u", name).Output(); err == nil {
+		if uid, err := strconv.Atoi(strings.TrimSpace(string(output))); err == nil && isSystemUID(uid) {
+			fmt.Printf("%s is a system account in the distro, using %s instead\n", name, fallbackDistroUser)
+			name = fallbackDistroUser
+		}
+	}
+	fmt.Printf("Creating user %s...\n", name)
+	cmd := wslCommand("-d", DistroName, "-u", "root", "-e", "sh", "-c", userSetupCommand(name, base))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create user %s: %s (%w)", name, strings.TrimSpace(string(output)), err)
+	}
+
+	conf, _ := wslCommand("-d", DistroName, "-u", "root", "-e", "cat", "/etc/wsl.conf").Output()
+	cmd = wslCommand("-d", DistroName, "-u", "root", "-e", "sh", "-c", "cat > /etc/wsl.conf")
+	cmd.Stdin = strings.NewReader(setWSLConfUser(string(conf), name))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to set the default user: %s (%w)", strings.TrimSpace(string(output)), err)
+	}
+
+	// wsl.conf is read when the distro starts
+	InvalidateEngineReady("")
+	wslCommand("--terminate", DistroName).Run()
+	return nil
+}
@@ -0,0 +1,120 @@
+package wsl
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestLinuxUserName(t *testing.T) {
+	tests := map[string]string{
+		"Wendel":                             "wendel",
+		"John Smith":                         "john-smith",
+		"j.doe":                              "j-doe",
+		"2fast":                              "_2fast",
+		"José":                               "jos",
+		"ÅÄÖ":                                "",
+		"a-very-long-windows-account-name-x": "a-very-long-windows-account-name",
+	}
+	for in, want := range tests {
+		if got := linuxUserName(in); got != want {
+			t.Errorf("linuxUserName(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
+
+func TestDistroUser(t *testing.T) {
+	t.Setenv("USERNAME", "Ana Souza")
+	if got := DistroUser(Config{}); got != "ana-souza" {
+		t.Errorf("Expected the Windows account name, got %q", got)
+	}
+	if got := DistroUser(Config{DistroUser: "dev"}); got != "dev" {
+		t.Errorf("Expected the configured user, got %q", got)
+	}
+	if got := DistroUser(Config{DistroUser: "root"}); got != "root" {
+		t.Errorf("Expected root to turn provisioning off, got %q", got)
+	}
+	t.Setenv("USERNAME", "Ümit")
+	if got := DistroUser(Config{}); got != "mit" {
+		t.Errorf("Unexpected user %q", got)
+	}
+	t.Setenv("USERNAME", "ÅÄÖ")
+	if got := DistroUser(Config{}); got != fallbackDistroUser {
+		t.Errorf("Expected the fallback user, got %q", got)
+	}
+}
+
+func TestSetWSLConfUser(t *testing.T) {
+	tests := []struct{ conf, want string }{
+		{"", "[user]\ndefault=ana\n"},
+		{"[boot]\nsystemd=false\n", "[boot]\nsystemd=false\n\n[user]\ndefault=ana\n"},
+		{"[user]\ndefault = root\n\n[boot]\ncommand=x\n", "[user]\ndefault=ana\n\n[boot]\ncommand=x\n"},
+		{"[boot]\ncommand=x\n[user]\n", "[boot]\ncommand=x\n[user]\ndefault=ana\n"},
+	}
+	for _, tt := range tests {
+		if got := setWSLConfUser(tt.conf, "ana"); got != tt.want {
+			t.Errorf("setWSLConfUser(%q) = %q; want %q", tt.conf, got, tt.want)
+		}
+	}
+}
+
+func TestEngineCommandArgs(t *testing.T) {
+	if got := strings.Join(engineCommandArgs("docker", "docker", "ps"), " "); got != "-d ezship -e docker ps" {
+		t.Errorf("docker should run as the default user, got %s", got)
+	}
+	if got := strings.Join(engineCommandArgs("nerdctl", "nerdctl", "ps"), " "); got != "-d ezship -u root -e nerdctl ps" {
+		t.Errorf("nerdctl should run as root, got %s", got)
+	}
+}
+
+func TestProvisionUser(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+	t.Setenv("USERNAME", "Ana")
+	var calls []string
+	orig := wslCommand
+	t.Cleanup(func() { wslCommand = orig })
+	wslCommand = func(args ...string) *exec.Cmd {
+		cmd := strings.Join(args, " ")
+		calls = append(calls, cmd)
+		if strings.HasSuffix(cmd, "cat /etc/wsl.conf") {
+			return stubCommand("[boot]\nsystemd=false\n")
+		}
+		return stubCommand("")
+	}
+
+	base, _ := GetBaseImage("alpine")
+	if err := provisionUser(base); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(calls, "\n")
+	for _, want := range []string{"adduser -D -s /bin/sh ana", "addgroup ana docker", "ana:100000:65536", "cat > /etc/wsl.conf", "--terminate ezship"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in the provisioning calls:\n%s", want, got)
+		}
+	}
+
+	// A name taken by a system account falls back to the ezship user
+	calls = nil
+	SaveConfig(Config{DistroUser: "daemon"})
+	wslCommand = func(args ...string) *exec.Cmd {
+		cmd := strings.Join(args, " ")
+		calls = append(calls, cmd)
+		if strings.HasSuffix(cmd, "id -u daemon") {
+			return stubCommand("1\n")
+		}
+		return stubCommand("")
+	}
+	if err := provisionUser(base); err != nil {
+		t.Fatal(err)
+	}
+	got = strings.Join(calls, "\n")
+	if strings.Contains(got, "addgroup daemon") || !strings.Contains(got, "addgroup ezship docker") {
+		t.Errorf("Expected the fallback user instead of the system account:\n%s", got)
+	}
+
+	calls = nil
+	SaveConfig(Config{DistroUser: "root"})
+	if err := provisionUser(base); err != nil || len(calls) != 0 {
+		t.Errorf("Expected no provisioning with distro_user root, got %v, %v", calls, err)
+	}
+}
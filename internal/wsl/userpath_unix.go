@@ -0,0 +1,27 @@
+//go:build !windows
+
+package wsl
+
+import (
+	"errors"
+	"os"
+)
+
+// userPath is only stored persistently on Windows
+func userPath() (string, error) {
+	return "", nil
+}
+
+func setUserPath(path string) error {
+	return errors.New("the user PATH can only be changed on Windows")
+}
+
+// deleteAfterExit removes the executable and its directory, if empty (a running binary can be
+// deleted outside Windows)
+func deleteAfterExit(exe, dir string) error {
+	if err := os.Remove(exe); err != nil {
+		return err
+	}
+	os.Remove(dir)
+	return nil
+}
@@ -0,0 +1,56 @@
+//go:build windows
+
+package wsl
+
+import (
+	"os/exec"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// userPath reads the user's PATH from the registry (where install.ps1 adds ezship)
+func userPath() (string, error) {
+	k, err := registry.OpenKey(registry.CURRENT_USER, "Environment", registry.QUERY_VALUE)
+	if err != nil {
+		return "", err
+	}
+	defer k.Close()
+	v, _, err := k.GetStringValue("Path")
+	if err == registry.ErrNotExist {
+		return "", nil
+	}
+	return v, err
+}
+
+// setUserPath writes the user's PATH and tells running programs (Explorer) about it
+func setUserPath(path string) error {
+	k, err := registry.OpenKey(registry.CURRENT_USER, "Environment", registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	if err := k.SetExpandStringValue("Path", path); err != nil {
+		return err
+	}
+	env, _ := syscall.UTF16PtrFromString("Environment")
+	const hwndBroadcast, wmSettingChange, smtoAbortIfHung = 0xffff, 0x001A, 0x0002
+	windows.NewLazySystemDLL("user32.dll").NewProc("SendMessageTimeoutW").Call(
+		hwndBroadcast, wmSettingChange, 0, uintptr(unsafe.Pointer(env)), smtoAbortIfHung, 5000, 0)
+	return nil
+}
+
+// deleteAfterExit removes the running executable (and then its directory, if empty) once this
+// process has exited, since Windows does not allow deleting a running .exe
+func deleteAfterExit(exe, dir string) error {
+	script := `ping -n 3 127.0.0.1 >nul & del /f /q "` + exe + `" & rmdir "` + dir + `"`
+	cmd := exec.Command("cmd.exe")
+	cmd.SysProcAttr = detachedProcAttr()
+	cmd.SysProcAttr.CmdLine = `cmd.exe /c "` + script + `"`
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
@@ -0,0 +1,209 @@
+package wsl
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Compaction methods for Vacuum
+const (
+	VacuumAuto        = ""             // Optimize-VHD when Hyper-V tools exist, diskpart otherwise
+	VacuumDiskpart    = "diskpart"     // 'compact vdisk' via diskpart (needs admin)
+	VacuumOptimizeVHD = "optimize-vhd" // Hyper-V's Optimize-VHD -Mode Full
+	VacuumSparse      = "sparse"       // WSL sparse VHD mode: the disk shrinks by itself after fstrim
+)
+
+// VacuumMethods lists the selectable compaction methods
+var VacuumMethods = []string{VacuumDiskpart, VacuumOptimizeVHD, VacuumSparse}
+
+// hostCommand builds a Windows-side command (diskpart, powershell). Tests swap it out.
+var hostCommand = exec.Command
+
+// VacuumOptions controls how Vacuum compacts the disk
+type VacuumOptions struct {
+	Method string // one of VacuumMethods, or VacuumAuto (vacuum_method in the config)
+	DryRun bool   // only estimate the reclaimable space
+}
+
+// VacuumResult reports what Vacuum did (or would do, for a dry run)
+type VacuumResult struct {
+	Method      string
+	VHDXPath    string
+	Before      int64 // vhdx size before compaction
+	After       int64 // vhdx size after compaction (0 for a dry run)
+	Used        int64 // bytes used by the distro's filesystem
+	Reclaimable int64 // estimate: vhdx size minus used bytes
+	Trimmed     string
+}
+
+// Saved returns the bytes recovered on the host
+func (r VacuumResult) Saved() int64 {
+	if r.After == 0 || r.After > r.Before {
+		return 0
+	}
+	return r.Before - r.After
+}
+
+// diskpartScript returns the diskpart commands compacting a vhdx. The disk is attached read-only
+// so diskpart can see which blocks are free.
+func diskpartScript(vhdxPath string) string {
+	return fmt.Sprintf("select vdisk file=\"%s\"\nattach vdisk readonly\ncompact vdisk\ndetach vdisk\n", vhdxPath)
+}
+
+// optimizeVHDCommand returns the PowerShell command compacting a vhdx with Hyper-V tools
+func optimizeVHDCommand(vhdxPath string) string {
+	return fmt.Sprintf("Optimize-VHD -Path '%s' -Mode Full", strings.ReplaceAll(vhdxPath, "'", "''"))
+}
+
+// hasOptimizeVHD reports whether the Hyper-V PowerShell module is available
+var hasOptimizeVHD = func() bool {
+	return hostCommand("powershell", "-NoProfile", "-Command", "Get-Command Optimize-VHD -ErrorAction Stop").Run() == nil
+}
+
+// parseDf extracts the size and used bytes from 'df -k /' output (GNU and busybox)
+func parseDf(output string) (size, used int64, err error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return 0, 0, fmt.Errorf("unexpected df output: %q", output)
+	}
+	// "Filesystem 1K-blocks Used Available Use% Mounted": long device names wrap onto their
+	// own line, so count from the end of the last one
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 5 {
+		return 0, 0, fmt.Errorf("unexpected df output: %q", output)
+	}
+	size, err1 := strconv.ParseInt(fields[len(fields)-5], 10, 64)
+	used, err2 := strconv.ParseInt(fields[len(fields)-4], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("unexpected df output: %q", output)
+	}
+	return size * 1024, used * 1024, nil
+}
+
+// distroFilesystem returns the size and used bytes of the distro's root filesystem
+func distroFilesystem() (size, used int64, err error) {
+	output, err := wslCommand("-d", DistroName, "-u", "root", "-e", "df", "-k", "/").Output()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read filesystem usage: %w", err)
+	}
+	return parseDf(string(output))
+}
+
+// resolveVacuumMethod picks the compaction method for the options and config
+func resolveVacuumMethod(opts VacuumOptions, cfg Config) (string, error) {
+	method := opts.Method
+	if method == VacuumAuto {
+		method = cfg.VacuumMethod
+	}
+	switch method {
+	case VacuumAuto:
+		if hasOptimizeVHD() {
+			return VacuumOptimizeVHD, nil
+		}
+		return VacuumDiskpart, nil
+	case VacuumDiskpart, VacuumOptimizeVHD, VacuumSparse:
+		return method, nil
+	}
+	return "", fmt.Errorf("unknown vacuum method %q (available: %s)", method, strings.Join(VacuumMethods, ", "))
+}
+
+// Vacuum trims the distro's filesystem and compacts its vhdx file to recover disk space
+func Vacuum(opts VacuumOptions) (*VacuumResult, error) {
+	var res *VacuumResult
+	err := withLock(OperationLock, "vacuuming disk", func() error {
+		var err error
+		res, err = vacuum(opts)
+		return err
+	})
+	return res, err
+}
+
+func vacuum(opts VacuumOptions) (*VacuumResult, error) {
+	vhdxPath := filepath.Join(GetInstallDir(), "ext4.vhdx")
+	stat, err := os.Stat(vhdxPath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("vhdx file not found at %s", vhdxPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := LoadConfig()
+	method, err := resolveVacuumMethod(opts, cfg)
+	if err != nil {
+		return nil, err
+	}
+	res := &VacuumResult{Method: method, VHDXPath: vhdxPath, Before: stat.Size()}
+
+	if _, used, err := distroFilesystem(); err == nil {
+		res.Used = used
+		if res.Before > used {
+			res.Reclaimable = res.Before - used
+		}
+	} else if opts.DryRun {
+		return nil, err
+	}
+	if opts.DryRun {
+		return res, nil
+	}
+
+	// The export is about as large as the data in the distro
+	if err := checkSnapshotSpace(cfg, res.Used); err != nil {
+		return nil, err
+	}
+	if err := takeSnapshot("vacuum"); err != nil {
+		return nil, err
+	}
+
+	// Release freed blocks first, otherwise compaction finds little to reclaim
+	output, err := wslCommand("-d", DistroName, "-u", "root", "-e", "fstrim", "-av").CombinedOutput()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: fstrim failed: %s\n", strings.TrimSpace(string(output)))
+	}
+	res.Trimmed = strings.TrimSpace(string(output))
+
+	InvalidateEngineReady("")
+	wslCommand("--terminate", DistroName).Run()
+
+	switch method {
+	case VacuumSparse:
+		// From now on WSL returns trimmed blocks to the host by itself
+		if output, err := wslCommand("--manage", DistroName, "--set-sparse", "true").CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("failed to enable sparse mode: %s (%w)", strings.TrimSpace(string(output)), err)
+		}
+	case VacuumOptimizeVHD:
+		cmd := hostCommand("powershell", "-NoProfile", "-Command", optimizeVHDCommand(vhdxPath))
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("Optimize-VHD failed: %s (%w)", strings.TrimSpace(string(output)), err)
+		}
+	default:
+		scriptPath := filepath.Join(os.TempDir(), "ezship_compact.txt")
+		if err := os.WriteFile(scriptPath, []byte(diskpartScript(vhdxPath)), 0644); err != nil {
+			return nil, fmt.Errorf("failed to create diskpart script: %w", err)
+		}
+		defer os.Remove(scriptPath)
+
+		cmd := hostCommand("diskpart", "/s", scriptPath)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("diskpart failed: %s (%w)", string(output), err)
+		}
+	}
+
+	if stat, err := os.Stat(vhdxPath); err == nil {
+		res.After = stat.Size()
+	}
+	return res, nil
+}
+
+// Summary describes the result in one line
+func (r VacuumResult) Summary() string {
+	if r.After == 0 {
+		return fmt.Sprintf("disk %s, used %s, about %s reclaimable with %s",
+			FormatSize(r.Before), FormatSize(r.Used), FormatSize(r.Reclaimable), r.Method)
+	}
+	return fmt.Sprintf("%s -> %s (saved %s, %s)", FormatSize(r.Before), FormatSize(r.After), FormatSize(r.Saved()), r.Method)
+}
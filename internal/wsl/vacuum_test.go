@@ -0,0 +1,128 @@
+package wsl
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const gnuDf = `Filesystem      1K-blocks    Used Available Use% Mounted on
+/dev/sdc       1055762868 4194304 997864828   1% /
+`
+
+func TestDiskpartScript(t *testing.T) {
+	got := diskpartScript(`D:\ezship\ext4.vhdx`)
+	want := "select vdisk file=\"D:\\ezship\\ext4.vhdx\"\nattach vdisk readonly\ncompact vdisk\ndetach vdisk\n"
+	if got != want {
+		t.Errorf("diskpartScript() = %q; want %q", got, want)
+	}
+
+	if got := optimizeVHDCommand(`C:\Users\O'Brien\ezship\ext4.vhdx`); got != `Optimize-VHD -Path 'C:\Users\O''Brien\ezship\ext4.vhdx' -Mode Full` {
+		t.Errorf("optimizeVHDCommand() = %s", got)
+	}
+}
+
+func TestParseDf(t *testing.T) {
+	tests := []struct {
+		name, output string
+		size, used   int64
+	}{
+		{"gnu", gnuDf, 1055762868 * 1024, 4194304 * 1024},
+		{"busybox", "Filesystem           1K-blocks      Used Available Use% Mounted on\noverlay               1000        10       990   1% /\n", 1000 * 1024, 10 * 1024},
+		{"wrapped", "Filesystem 1K-blocks Used Available Use% Mounted on\n/dev/mapper/very-long-volume-name\n 2000 500 1500 25% /\n", 2000 * 1024, 500 * 1024},
+	}
+	for _, tt := range tests {
+		size, used, err := parseDf(tt.output)
+		if err != nil || size != tt.size || used != tt.used {
+			t.Errorf("%s: parseDf() = %d, %d, %v; want %d, %d", tt.name, size, used, err, tt.size, tt.used)
+		}
+	}
+	if _, _, err := parseDf("garbage"); err == nil {
+		t.Error("Expected error for unparsable output")
+	}
+}
+
+func TestResolveVacuumMethod(t *testing.T) {
+	orig := hasOptimizeVHD
+	t.Cleanup(func() { hasOptimizeVHD = orig })
+
+	hasOptimizeVHD = func() bool { return false }
+	if m, _ := resolveVacuumMethod(VacuumOptions{}, Config{}); m != VacuumDiskpart {
+		t.Errorf("Expected diskpart without Hyper-V tools, got %s", m)
+	}
+	hasOptimizeVHD = func() bool { return true }
+	if m, _ := resolveVacuumMethod(VacuumOptions{}, Config{}); m != VacuumOptimizeVHD {
+		t.Errorf("Expected Optimize-VHD with Hyper-V tools, got %s", m)
+	}
+	if m, _ := resolveVacuumMethod(VacuumOptions{}, Config{VacuumMethod: VacuumSparse}); m != VacuumSparse {
+		t.Errorf("Expected the configured method, got %s", m)
+	}
+	if m, _ := resolveVacuumMethod(VacuumOptions{Method: VacuumDiskpart}, Config{VacuumMethod: VacuumSparse}); m != VacuumDiskpart {
+		t.Errorf("Expected the flag to override the config, got %s", m)
+	}
+	if _, err := resolveVacuumMethod(VacuumOptions{Method: "defrag"}, Config{}); err == nil {
+		t.Error("Expected error for an unknown method")
+	}
+}
+
+// stubVacuumBackend fakes wsl.exe and diskpart, returning the recorded wsl calls and diskpart scripts
+func stubVacuumBackend(t *testing.T) (*wslRecorder, *[]string) {
+	t.Helper()
+	calls := stubWSL(t, stubResponse{match: "df -k /", output: "Filesystem 1K-blocks Used Available Use% Mounted on\n/dev/sdc 8192 2048 6144 25% /\n"})
+	os.MkdirAll(GetInstallDir(), 0755)
+	os.WriteFile(filepath.Join(GetInstallDir(), "ext4.vhdx"), make([]byte, 8<<20), 0644)
+
+	var scripts []string
+	origHost := hostCommand
+	hostCommand = func(name string, args ...string) *exec.Cmd {
+		if name == "diskpart" {
+			data, _ := os.ReadFile(args[1])
+			scripts = append(scripts, string(data))
+			// Pretend the compaction shrank the disk
+			os.Truncate(filepath.Join(GetInstallDir(), "ext4.vhdx"), 3<<20)
+		}
+		return stubCommand("")
+	}
+	t.Cleanup(func() { hostCommand = origHost })
+	return calls, &scripts
+}
+
+func TestVacuumDryRun(t *testing.T) {
+	calls, scripts := stubVacuumBackend(t)
+
+	res, err := Vacuum(VacuumOptions{Method: VacuumDiskpart, DryRun: true})
+	if err != nil {
+		t.Fatalf("Vacuum dry run failed: %v", err)
+	}
+	if res.Before != 8<<20 || res.Used != 2<<20 || res.Reclaimable != 6<<20 {
+		t.Errorf("Unexpected estimate: %+v", res)
+	}
+	if len(*scripts) != 0 || len(calls.Calls()) != 1 {
+		t.Errorf("Expected a dry run to change nothing, got %v and %d diskpart runs", calls.Calls(), len(*scripts))
+	}
+}
+
+func TestVacuumDiskpart(t *testing.T) {
+	calls, scripts := stubVacuumBackend(t)
+
+	res, err := Vacuum(VacuumOptions{Method: VacuumDiskpart})
+	if err != nil {
+		t.Fatalf("Vacuum failed: %v", err)
+	}
+	if res.Before != 8<<20 || res.After != 3<<20 || res.Saved() != 5<<20 {
+		t.Errorf("Unexpected sizes: %+v", res)
+	}
+
+	joined := strings.Join(calls.Calls(), "\n")
+	trim := strings.Index(joined, "fstrim -av")
+	term := strings.Index(joined, "--terminate ezship")
+	if trim < 0 || term < trim {
+		t.Errorf("Expected fstrim before the distro is terminated, got:\n%s", joined)
+	}
+	vhdx := filepath.Join(GetInstallDir(), "ext4.vhdx")
+	if len(*scripts) != 1 || (*scripts)[0] != diskpartScript(vhdx) {
+		t.Errorf("Unexpected diskpart script: %q", *scripts)
+	}
+}